@@ -5,20 +5,57 @@
 // - Idempotency Key injection
 // - Basic Auth
 // - Bearer token via request builder
+// - AWS Signature Version 4
+// - JWT token source with refresh-token rotation
+// - OAuth2 client credentials token source
+// - API key in a header, query parameter or cookie
+// - Mutual TLS with certificate hot reload
+// - HTTP Digest authentication
+// - Token caching with single-flight refresh
+// - HMAC signing over a request body hash
+// - Idempotency keys from a custom key function
 package auth
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
 	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"log"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/google/uuid"
 	"github.com/n0l3r/httpx"
 	httpxauth "github.com/n0l3r/httpx/auth"
+	"golang.org/x/sync/singleflight"
 )
 
 // Run executes all auth examples.
@@ -34,6 +71,15 @@ func Run() {
 	exampleIdempotencyKey()
 	exampleBasicAuth()
 	exampleBearerTokenBuilder()
+	exampleAWSSigV4()
+	exampleJWTTokenSource()
+	exampleClientCredentials()
+	exampleAPIKey()
+	exampleMutualTLS()
+	exampleDigestAuth()
+	exampleTokenCaching()
+	exampleHMACBodyHash()
+	exampleIdempotencyKeyFunc()
 }
 
 // [1] OAuth 1.0a signing.
@@ -236,8 +282,1106 @@ func exampleBearerTokenBuilder() {
 	fmt.Printf("  ✓ Authorization: %s\n", gotAuth)
 }
 
+// [8] AWS Signature Version 4.
+func exampleAWSSigV4() {
+	fmt.Println("\n[8] AWS Signature v4 — signed with aws-sdk-go-v2")
+
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	creds := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+			SessionToken:    "session-token-example",
+		}, nil
+	})
+	transport := &sigV4Transport{Region: "eu-west-1", Service: "execute-api", Credentials: creds}
+
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(transport))
+	resp, err := c.Post(context.Background(), "/prod/orders", httpx.WithJSONBody(map[string]int{"qty": 1}))
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+
+	authz := got.Get("Authorization")
+	fmt.Printf("  ✓ status=%d\n", resp.StatusCode())
+	fmt.Printf("    Algorithm:      %v\n", strings.HasPrefix(authz, "AWS4-HMAC-SHA256 "))
+	for _, part := range strings.Split(strings.TrimPrefix(authz, "AWS4-HMAC-SHA256 "), ", ") {
+		fmt.Printf("    %s\n", truncate(part, 70))
+	}
+	fmt.Printf("    X-Amz-Date:     %s\n", got.Get("X-Amz-Date"))
+	fmt.Printf("    Security token: %v\n", got.Get("X-Amz-Security-Token") != "")
+}
+
+// [9] JWT token source — refresh shortly before the access token expires.
+func exampleJWTTokenSource() {
+	fmt.Println("\n[9] JWT token source — refresh-token grant before exp")
+
+	var refreshes atomic.Int32
+	authSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("grant_type") != "refresh_token" || r.FormValue("refresh_token") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		n := refreshes.Add(1)
+		time.Sleep(20 * time.Millisecond) // let concurrent callers pile up
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  fakeJWT(fmt.Sprintf("user-%d", n), time.Now().Add(time.Hour)),
+			"refresh_token": fmt.Sprintf("refresh-%d", n+1),
+			"token_type":    "Bearer",
+		})
+	}))
+	defer authSrv.Close()
+
+	var (
+		mu  sync.Mutex
+		got = map[string]int{}
+	)
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got[r.Header.Get("Authorization")]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	source := &jwtTokenSource{
+		AccessToken:  fakeJWT("user-0", time.Now().Add(30*time.Second)), // inside the 60s buffer
+		RefreshToken: "refresh-1",
+		RefreshURL:   authSrv.URL,
+		Buffer:       time.Minute,
+	}
+	c, _ := httpx.New(httpx.WithBaseURL(api.URL), httpx.WithTransport(&httpxauth.OAuth2Transport{Source: source}))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Get(context.Background(), "/me")
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("  ✓ 5 concurrent calls → %d refresh(es)\n", refreshes.Load())
+	for token, n := range got {
+		fmt.Printf("    %d× %s\n", n, truncate(token, 60))
+	}
+	fmt.Printf("    next refresh token: %s\n", source.RefreshToken)
+}
+
+// [10] Client credentials — machine-to-machine OAuth2, cached until expiry.
+func exampleClientCredentials() {
+	fmt.Println("\n[10] OAuth2 client credentials — token cached for expires_in")
+
+	var issued atomic.Int32
+	authSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != "billing-svc" || secret != "s3cr3t" || r.FormValue("grant_type") != "client_credentials" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		n := issued.Add(1)
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": fmt.Sprintf("cc-token-%d[%s]", n, r.FormValue("scope")),
+			"token_type":   "Bearer",
+			"expires_in":   1,
+		})
+	}))
+	defer authSrv.Close()
+
+	var got []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer api.Close()
+
+	source := &clientCredentialsTokenSource{
+		ClientID:     "billing-svc",
+		ClientSecret: "s3cr3t",
+		TokenURL:     authSrv.URL,
+		Scopes:       []string{"invoices:read", "invoices:write"},
+	}
+	c, _ := httpx.New(httpx.WithBaseURL(api.URL), httpx.WithTransport(&httpxauth.OAuth2Transport{Source: source}))
+
+	for range 3 {
+		c.Get(context.Background(), "/invoices")
+	}
+	time.Sleep(1100 * time.Millisecond) // token expires
+	c.Get(context.Background(), "/invoices")
+
+	fmt.Printf("  ✓ 4 calls → %d token request(s)\n", issued.Load())
+	for i, a := range got {
+		fmt.Printf("    call %d: %s\n", i+1, a)
+	}
+}
+
+// [11] API key — header, query parameter or cookie.
+func exampleAPIKey() {
+	fmt.Println("\n[11] API key placement — header, query, cookie")
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("X-API-Key") != "":
+			got = "header X-API-Key=" + r.Header.Get("X-API-Key")
+		case r.URL.Query().Get("api_key") != "":
+			got = "query  " + r.URL.RawQuery
+		default:
+			if ck, err := r.Cookie("session_key"); err == nil {
+				got = "cookie " + ck.String()
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	for _, t := range []*apiKeyTransport{
+		{Key: "X-API-Key", Value: "k-header", Placement: headerPlacement},
+		{Key: "api_key", Value: "k-query", Placement: queryPlacement},
+		{Key: "session_key", Value: "k-cookie", Placement: cookiePlacement},
+	} {
+		c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(t))
+		c.Get(context.Background(), "/search?q=go")
+		fmt.Printf("  ✓ %s\n", got)
+	}
+}
+
+// [12] Mutual TLS — client certificate required, rotated without restart.
+func exampleMutualTLS() {
+	fmt.Println("\n[12] Mutual TLS — client certificate, hot reload")
+
+	dir, err := os.MkdirTemp("", "mtls-example")
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	ca, caKey, caPEM, _, err := issueCert("example-ca", nil, nil)
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	_, _, srvCertPEM, srvKeyPEM, _ := issueCert("localhost", ca, caKey)
+	_, _, cliCertPEM, cliKeyPEM, _ := issueCert("billing-svc", ca, caKey)
+	serverCert, _ := tls.X509KeyPair(srvCertPEM, srvKeyPEM)
+
+	files := map[string][]byte{"ca.pem": caPEM, "client.pem": cliCertPEM, "client-key.pem": cliKeyPEM}
+	for name, b := range files {
+		os.WriteFile(filepath.Join(dir, name), b, 0o600)
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello %s", r.TLS.PeerCertificates[0].Subject.CommonName)
+	}))
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0) // silence the rejected handshake
+	srv.StartTLS()
+	defer srv.Close()
+
+	// Trusts the server but presents no certificate.
+	anon, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(&http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: pool},
+	}))
+	_, err = anon.Get(context.Background(), "/")
+	fmt.Printf("  ✓ without client cert: rejected=%v\n", err != nil)
+
+	transport := &mtlsTransport{
+		CertFile: filepath.Join(dir, "client.pem"),
+		KeyFile:  filepath.Join(dir, "client-key.pem"),
+		CAFile:   filepath.Join(dir, "ca.pem"),
+	}
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(transport))
+	if resp, err := c.Get(context.Background(), "/"); err == nil {
+		fmt.Printf("  ✓ with client cert:    %s\n", resp.String())
+	} else {
+		fmt.Printf("  ✗ %v\n", err)
+	}
+
+	stop := transport.WatchFiles(20 * time.Millisecond)
+	defer stop()
+	_, _, cliCertPEM, cliKeyPEM, _ = issueCert("billing-svc-rotated", ca, caKey)
+	os.WriteFile(transport.KeyFile, cliKeyPEM, 0o600)
+	os.WriteFile(transport.CertFile, cliCertPEM, 0o600)
+	time.Sleep(100 * time.Millisecond)
+
+	if resp, err := c.Get(context.Background(), "/"); err == nil {
+		fmt.Printf("  ✓ after rotation:      %s\n", resp.String())
+	} else {
+		fmt.Printf("  ✗ %v\n", err)
+	}
+}
+
+// [13] Digest auth — challenge, response, nonce reuse with nc counting.
+func exampleDigestAuth() {
+	fmt.Println("\n[13] HTTP Digest auth (SHA-256, qop=auth)")
+
+	const realm, user, pass = "api@example.com", "alice", "wonderland"
+	nonce := "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	var (
+		mu       sync.Mutex
+		lastNC   int64
+		requests int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+
+		p := parseAuthParams(strings.TrimPrefix(r.Header.Get("Authorization"), "Digest "))
+		nc, _ := strconv.ParseInt(p["nc"], 16, 64)
+		ha1 := digestHash("SHA-256", user+":"+realm+":"+pass)
+		ha2 := digestHash("SHA-256", r.Method+":"+p["uri"])
+		want := digestHash("SHA-256", strings.Join([]string{ha1, p["nonce"], p["nc"], p["cnonce"], "auth", ha2}, ":"))
+		if p["username"] != user || p["nonce"] != nonce || p["response"] != want || nc <= lastNC {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Digest realm=%q, qop="auth", algorithm=SHA-256, nonce=%q, opaque="5ccc069c403ebaf9f0171e9517f40e41"`,
+				realm, nonce))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		lastNC = nc
+		fmt.Fprintf(w, "welcome %s (nc=%s)", user, p["nc"])
+	}))
+	defer srv.Close()
+
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(&digestTransport{Username: user, Password: pass}))
+	for _, path := range []string{"/dir/index.html", "/dir/other.html"} {
+		resp, err := c.Get(context.Background(), path)
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			continue
+		}
+		fmt.Printf("  ✓ %s → %d %s\n", path, resp.StatusCode(), resp.String())
+	}
+	fmt.Printf("    server saw %d requests (1 challenge + 2 authorized)\n", requests)
+}
+
+// [14] Token caching — wrap any TokenSource, one fetch per TTL window.
+func exampleTokenCaching() {
+	fmt.Println("\n[14] Token caching — 100 concurrent calls, one fetch per TTL")
+
+	var fetches atomic.Int32
+	slow := tokenSourceFunc(func(ctx context.Context) (string, error) {
+		n := fetches.Add(1)
+		time.Sleep(50 * time.Millisecond) // e.g. a metadata-server round trip
+		return fmt.Sprintf("token-%d", n), nil
+	})
+
+	var (
+		mu  sync.Mutex
+		got = map[string]int{}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		got[r.Header.Get("Authorization")]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	source := &tokenCachingSource{Inner: slow, TTL: 200 * time.Millisecond}
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(&httpxauth.OAuth2Transport{Source: source}))
+
+	burst := func() {
+		var wg sync.WaitGroup
+		for range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.Get(context.Background(), "/data")
+			}()
+		}
+		wg.Wait()
+	}
+	burst()
+	time.Sleep(250 * time.Millisecond) // TTL elapses
+	burst()
+
+	fmt.Printf("  ✓ 200 calls in 2 TTL windows → %d fetch(es) from the inner source\n", fetches.Load())
+	for token, n := range got {
+		fmt.Printf("    %d× %s\n", n, token)
+	}
+}
+
+// [15] HMAC signing with a body hash — a replayed signature with a swapped
+// body is rejected.
+func exampleHMACBodyHash() {
+	fmt.Println("\n[15] HMAC signing over the request body hash")
+
+	secret := []byte("super-secret-key")
+	var (
+		mu   sync.Mutex
+		sigs []string
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(sum[:])
+
+		parts := parseSignature(r.Header.Get("X-Signature"))
+		msg := strings.Join([]string{r.Method, "http://" + r.Host + r.URL.RequestURI(), parts["ts"], bodyHash}, "\n")
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(msg))
+		if r.Header.Get("X-Body-Hash") != bodyHash || parts["sig"] != hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		sigs = append(sigs, parts["sig"])
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	transport := &hmacBodyTransport{
+		Config:          httpxauth.HMACConfig{KeyID: "key-2024", Secret: secret, Header: "X-Signature"},
+		IncludeBodyHash: true,
+	}
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(transport))
+
+	for _, amount := range []int{100, 999} {
+		resp, err := c.Post(context.Background(), "/transfers", httpx.WithJSONBody(map[string]int{"amount": amount}))
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			continue
+		}
+		fmt.Printf("  ✓ amount=%d → %d\n", amount, resp.StatusCode())
+	}
+	if len(sigs) == 2 {
+		fmt.Printf("    signatures differ with the body: %v\n", sigs[0] != sigs[1])
+	}
+
+	// Replay a captured signature with a tampered body.
+	var captured http.Header
+	capture := &hmacBodyTransport{
+		Config:          transport.Config,
+		IncludeBodyHash: true,
+		Base: httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			captured = req.Header.Clone()
+			return http.DefaultTransport.RoundTrip(req)
+		}),
+	}
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/transfers", strings.NewReader(`{"amount":100}`))
+	if resp, err := capture.RoundTrip(req); err == nil {
+		resp.Body.Close()
+	}
+	replay, _ := http.NewRequest(http.MethodPost, srv.URL+"/transfers", strings.NewReader(`{"amount":100000}`))
+	replay.Header = captured
+	resp, err := http.DefaultClient.Do(replay)
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	resp.Body.Close()
+	fmt.Printf("  ✓ replay with swapped body → %d\n", resp.StatusCode)
+}
+
+// [16] Idempotency key from a KeyFunc — identical requests share a key.
+func exampleIdempotencyKeyFunc() {
+	fmt.Println("\n[16] Idempotency key — content-hash KeyFunc")
+
+	var keys []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	transport := &idempotencyKeyTransport{Header: "Idempotency-Key", KeyFunc: contentHashKey}
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(transport))
+
+	for _, amount := range []int{100, 100, 250} {
+		c.Post(context.Background(), "/payments", httpx.WithJSONBody(map[string]int{"amount": amount}))
+	}
+	if len(keys) == 3 {
+		fmt.Printf("  ✓ identical requests share a key: %v\n", keys[0] == keys[1])
+		fmt.Printf("  ✓ different body, different key: %v\n", keys[0] != keys[2])
+		for i, k := range keys {
+			fmt.Printf("    req %d: %s\n", i+1, truncate(k, 24))
+		}
+	}
+
+	// Without a KeyFunc every request gets a fresh UUID.
+	keys = nil
+	fallback := &idempotencyKeyTransport{Header: "Idempotency-Key"}
+	c, _ = httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(fallback))
+	for range 2 {
+		c.Post(context.Background(), "/payments", httpx.WithJSONBody(map[string]int{"amount": 100}))
+	}
+	if len(keys) == 2 {
+		fmt.Printf("  ✓ nil KeyFunc → UUIDs %s, %s\n", keys[0], keys[1])
+	}
+}
+
 // ---
 
+// tokenResponse is the OAuth2 token endpoint response (RFC 6749 §5.1).
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// requestToken posts form to an OAuth2 token endpoint. Client credentials,
+// when given, are sent with HTTP Basic auth.
+func requestToken(ctx context.Context, client *http.Client, tokenURL string, form url.Values, clientID, clientSecret string) (*tokenResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint: status %d", resp.StatusCode)
+	}
+
+	var tok tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return nil, fmt.Errorf("token endpoint: %w", err)
+	}
+	if tok.AccessToken == "" {
+		return nil, errors.New("token endpoint: no access_token in response")
+	}
+	return &tok, nil
+}
+
+// clientCredentialsTokenSource is an httpxauth TokenSource for the OAuth2
+// client credentials grant. The token is fetched on first use and reused
+// until it expires; concurrent callers share one fetch.
+type clientCredentialsTokenSource struct {
+	ClientID     string
+	ClientSecret string
+	TokenURL     string
+	Scopes       []string
+	Client       *http.Client // nil means http.DefaultClient
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+func (s *clientCredentialsTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Before(s.expires) {
+		return s.token, nil
+	}
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(s.Scopes) > 0 {
+		form.Set("scope", strings.Join(s.Scopes, " "))
+	}
+	tok, err := requestToken(ctx, s.Client, s.TokenURL, form, s.ClientID, s.ClientSecret)
+	if err != nil {
+		return "", fmt.Errorf("client credentials: %w", err)
+	}
+	s.token = tok.AccessToken
+	s.expires = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return s.token, nil
+}
+
+// jwtTokenSource is an httpxauth TokenSource for JWT access tokens. It reads
+// the token's exp claim and, once expiry is within Buffer, exchanges
+// RefreshToken at RefreshURL (OAuth2 refresh_token grant). Concurrent
+// callers wait for a single refresh.
+type jwtTokenSource struct {
+	AccessToken  string
+	RefreshToken string
+	RefreshURL   string
+	Client       *http.Client  // nil means http.DefaultClient
+	Buffer       time.Duration // refresh this long before exp
+
+	mu sync.Mutex
+}
+
+func (s *jwtTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exp, err := jwtExpiry(s.AccessToken)
+	if err == nil && time.Until(exp) > s.Buffer {
+		return s.AccessToken, nil
+	}
+	if err := s.refresh(ctx); err != nil {
+		return "", err
+	}
+	return s.AccessToken, nil
+}
+
+func (s *jwtTokenSource) refresh(ctx context.Context) error {
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {s.RefreshToken}}
+	tok, err := requestToken(ctx, s.Client, s.RefreshURL, form, "", "")
+	if err != nil {
+		return fmt.Errorf("jwt refresh: %w", err)
+	}
+	s.AccessToken = tok.AccessToken
+	if tok.RefreshToken != "" { // rotated
+		s.RefreshToken = tok.RefreshToken
+	}
+	return nil
+}
+
+// jwtExpiry returns the exp claim of a JWT. The signature is not checked:
+// the client only needs to know when to refresh.
+func jwtExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("jwt: malformed token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("jwt: %w", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("jwt: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, errors.New("jwt: no exp claim")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// fakeJWT builds an unsigned JWT carrying sub and exp, for the examples.
+func fakeJWT(sub string, exp time.Time) string {
+	enc := base64.RawURLEncoding
+	header := enc.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+	claims, _ := json.Marshal(map[string]any{"sub": sub, "exp": exp.Unix()})
+	return header + "." + enc.EncodeToString(claims) + "."
+}
+
+// sigV4Transport signs each request with AWS Signature Version 4 using the
+// aws-sdk-go-v2 signer: canonical request, SHA-256 payload hash,
+// Authorization and X-Amz-Date, plus X-Amz-Security-Token for temporary
+// credentials. Headers are set on a clone and a body without GetBody is
+// buffered into the clone; the caller's request is left alone.
+type sigV4Transport struct {
+	Region      string
+	Service     string
+	Credentials aws.CredentialsProvider
+	Base        http.RoundTripper // nil means http.DefaultTransport
+}
+
+func (t *sigV4Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	creds, err := t.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("sigv4: retrieve credentials: %w", err)
+	}
+
+	out := req.Clone(ctx)
+	payload, err := readBody(out)
+	if err != nil {
+		return nil, fmt.Errorf("sigv4: read body: %w", err)
+	}
+	sum := sha256.Sum256(payload)
+	if err := v4.NewSigner().SignHTTP(ctx, creds, out, hex.EncodeToString(sum[:]),
+		t.Service, t.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("sigv4: sign: %w", err)
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(out)
+}
+
+// readBody returns the bytes of out's body, where out is the transport's
+// clone of the caller's request. GetBody is used when available so the body
+// is not touched. Otherwise the body is read, as the RoundTripper contract
+// allows, and out gets an unread copy and a GetBody for replays; the
+// caller's request is never modified.
+func readBody(out *http.Request) ([]byte, error) {
+	if out.Body == nil || out.Body == http.NoBody {
+		return nil, nil
+	}
+	if out.GetBody != nil {
+		body, err := out.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	b, err := io.ReadAll(out.Body)
+	out.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	out.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(b)), nil }
+	out.Body, _ = out.GetBody()
+	return b, nil
+}
+
+// hmacBodyTransport signs requests like httpxauth.HMACTransport
+// (keyId=...,ts=...,sig=... over method, URL and timestamp) and, with
+// IncludeBodyHash, appends the hex SHA-256 of the body to the signing string
+// and sends it as X-Body-Hash. The body is buffered, not consumed.
+type hmacBodyTransport struct {
+	Config          httpxauth.HMACConfig
+	IncludeBodyHash bool
+	Base            http.RoundTripper // nil means http.DefaultTransport
+}
+
+func (t *hmacBodyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	header := t.Config.Header
+	if header == "" {
+		header = "X-Signature"
+	}
+
+	out := req.Clone(req.Context())
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	fields := []string{out.Method, out.URL.String(), ts}
+	if t.IncludeBodyHash {
+		body, err := readBody(out)
+		if err != nil {
+			return nil, fmt.Errorf("hmac: read body: %w", err)
+		}
+		sum := sha256.Sum256(body)
+		bodyHash := hex.EncodeToString(sum[:])
+		fields = append(fields, bodyHash)
+		out.Header.Set("X-Body-Hash", bodyHash)
+	}
+
+	mac := hmac.New(sha256.New, t.Config.Secret)
+	mac.Write([]byte(strings.Join(fields, "\n")))
+	out.Header.Set(header, fmt.Sprintf("keyId=%s,ts=%s,sig=%s", t.Config.KeyID, ts, hex.EncodeToString(mac.Sum(nil))))
+	return base.RoundTrip(out)
+}
+
+// idempotencyKeyTransport sets Header on non-GET/HEAD/OPTIONS requests that
+// do not already carry one. KeyFunc derives the key from the request; when
+// nil, a random UUID is used.
+type idempotencyKeyTransport struct {
+	Header  string
+	KeyFunc func(req *http.Request) string
+	Base    http.RoundTripper // nil means http.DefaultTransport
+}
+
+func (t *idempotencyKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return base.RoundTrip(req)
+	}
+	if req.Header.Get(t.Header) != "" {
+		return base.RoundTrip(req)
+	}
+
+	out := req.Clone(req.Context())
+	key := ""
+	if t.KeyFunc != nil {
+		// Buffer the body first so a KeyFunc reading it, like
+		// contentHashKey, goes through GetBody and leaves req intact.
+		if _, err := readBody(out); err != nil {
+			return nil, fmt.Errorf("idempotency key: read body: %w", err)
+		}
+		key = t.KeyFunc(out)
+	}
+	if key == "" {
+		key = uuid.NewString()
+	}
+	out.Header.Set(t.Header, key)
+	return base.RoundTrip(out)
+}
+
+// contentHashKey is a KeyFunc giving the hex SHA-256 of method, URL and
+// body, so a retried or resubmitted request carries the same key. It is
+// called with idempotencyKeyTransport's clone, whose body is already
+// replayable through GetBody.
+func contentHashKey(req *http.Request) string {
+	body, err := readBody(req)
+	if err != nil {
+		return ""
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n", req.Method, req.URL)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// apiKeyPlacement says where apiKeyTransport puts the key.
+type apiKeyPlacement int
+
+const (
+	headerPlacement apiKeyPlacement = iota
+	queryPlacement
+	cookiePlacement
+)
+
+// apiKeyTransport adds an API key to every request: as header Key, query
+// parameter Key or cookie Key. It works on a clone, so the caller's request
+// is never modified and concurrent use is safe.
+type apiKeyTransport struct {
+	Key       string
+	Value     string
+	Placement apiKeyPlacement
+	Base      http.RoundTripper // nil means http.DefaultTransport
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	switch t.Placement {
+	case headerPlacement:
+		out.Header.Set(t.Key, t.Value)
+	case queryPlacement:
+		q := out.URL.Query()
+		q.Set(t.Key, t.Value)
+		out.URL.RawQuery = q.Encode()
+	case cookiePlacement:
+		out.AddCookie(&http.Cookie{Name: t.Key, Value: t.Value})
+	default:
+		return nil, fmt.Errorf("api key: unknown placement %d", t.Placement)
+	}
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(out)
+}
+
+// mtlsTransport presents a client certificate loaded from CertFile/KeyFile
+// and trusts servers signed by CAFile. WatchFiles reloads all three when
+// they change on disk, so certificates can be rotated without a restart.
+type mtlsTransport struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+
+	once    sync.Once
+	initErr error
+	current atomic.Pointer[http.Transport]
+}
+
+func (t *mtlsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.once.Do(func() { t.initErr = t.reload() })
+	if t.initErr != nil {
+		return nil, t.initErr
+	}
+	return t.current.Load().RoundTrip(req)
+}
+
+// reload builds a transport from the files on disk and swaps it in. New
+// connections use the new certificate; the old transport's idle ones are
+// closed.
+func (t *mtlsTransport) reload() error {
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return fmt.Errorf("mtls: load key pair: %w", err)
+	}
+	caPEM, err := os.ReadFile(t.CAFile)
+	if err != nil {
+		return fmt.Errorf("mtls: read CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return errors.New("mtls: no certificates in CA file")
+	}
+
+	next := http.DefaultTransport.(*http.Transport).Clone()
+	next.TLSClientConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	if old := t.current.Swap(next); old != nil {
+		old.CloseIdleConnections()
+	}
+	return nil
+}
+
+// WatchFiles polls the certificate, key and CA files every interval and
+// reloads them after a change. A failed reload keeps the previous
+// certificates. The returned function stops watching.
+func (t *mtlsTransport) WatchFiles(interval time.Duration) (stop func()) {
+	t.once.Do(func() { t.initErr = t.reload() })
+
+	modTimes := func() (out [3]time.Time) {
+		for i, name := range []string{t.CertFile, t.KeyFile, t.CAFile} {
+			if fi, err := os.Stat(name); err == nil {
+				out[i] = fi.ModTime()
+			}
+		}
+		return out
+	}
+
+	last := modTimes()
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if now := modTimes(); now != last {
+					if t.reload() == nil {
+						last = now
+					}
+				}
+			}
+		}
+	}()
+	var stopOnce sync.Once
+	return func() { stopOnce.Do(func() { close(done) }) }
+}
+
+// issueCert creates an ECDSA certificate for cn, valid for localhost and
+// 127.0.0.1, signed by parent — or a self-signed CA when parent is nil.
+func issueCert(cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	signer, signerKey := parent, parentKey
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+		signer, signerKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return cert, key, certPEM, keyPEM, nil
+}
+
+// digestTransport implements HTTP Digest authentication (RFC 7616) with
+// qop=auth and MD5 or SHA-256. The first 401 challenge is answered by
+// replaying the request; later requests reuse the nonce with an increasing
+// nonce count until the server asks for a new one.
+type digestTransport struct {
+	Username string
+	Password string
+	Base     http.RoundTripper // nil means http.DefaultTransport
+
+	mu        sync.Mutex
+	challenge map[string]string
+	nc        int
+}
+
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	out := req.Clone(req.Context())
+	if _, err := readBody(out); err != nil { // make the body replayable
+		return nil, fmt.Errorf("digest: read body: %w", err)
+	}
+
+	if authz, ok := t.authorize(out); ok {
+		out.Header.Set("Authorization", authz)
+	}
+	resp, err := base.RoundTrip(out)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	www := resp.Header.Get("WWW-Authenticate")
+	if !strings.HasPrefix(www, "Digest ") {
+		return resp, nil
+	}
+
+	// New or stale nonce: answer the challenge once.
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	t.mu.Lock()
+	t.challenge, t.nc = parseAuthParams(strings.TrimPrefix(www, "Digest ")), 0
+	t.mu.Unlock()
+
+	retry := out.Clone(out.Context())
+	if out.GetBody != nil {
+		if retry.Body, err = out.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	authz, _ := t.authorize(retry)
+	retry.Header.Set("Authorization", authz)
+	return base.RoundTrip(retry)
+}
+
+// authorize builds the Authorization header for req from the current
+// challenge, incrementing the nonce count. It reports false before the
+// first challenge.
+func (t *digestTransport) authorize(req *http.Request) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.challenge == nil {
+		return "", false
+	}
+	t.nc++
+
+	ch := t.challenge
+	alg := ch["algorithm"]
+	if alg == "" {
+		alg = "MD5"
+	}
+	cnonceBytes := make([]byte, 8)
+	rand.Read(cnonceBytes)
+	cnonce := hex.EncodeToString(cnonceBytes)
+	nc := fmt.Sprintf("%08x", t.nc)
+	uri := req.URL.RequestURI()
+
+	ha1 := digestHash(alg, t.Username+":"+ch["realm"]+":"+t.Password)
+	ha2 := digestHash(alg, req.Method+":"+uri)
+	response := digestHash(alg, strings.Join([]string{ha1, ch["nonce"], nc, cnonce, "auth", ha2}, ":"))
+
+	authz := fmt.Sprintf(`Digest username=%q, realm=%q, nonce=%q, uri=%q, algorithm=%s, qop=auth, nc=%s, cnonce=%q, response=%q`,
+		t.Username, ch["realm"], ch["nonce"], uri, alg, nc, cnonce, response)
+	if opaque := ch["opaque"]; opaque != "" {
+		authz += fmt.Sprintf(", opaque=%q", opaque)
+	}
+	return authz, true
+}
+
+// digestHash hex-encodes the MD5 or SHA-256 hash of s, per the challenge's
+// algorithm.
+func digestHash(algorithm, s string) string {
+	var h hash.Hash
+	if strings.EqualFold(algorithm, "SHA-256") {
+		h = sha256.New()
+	} else {
+		h = md5.New()
+	}
+	h.Write([]byte(s))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseAuthParams parses comma-separated key=value auth parameters, where
+// values may be quoted strings containing commas.
+func parseAuthParams(s string) map[string]string {
+	out := map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; {
+		key, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				break
+			}
+			value, rest = rest[1:end+1], rest[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			rest = "," + rest
+		}
+		out[key] = strings.TrimSpace(value)
+		_, s, _ = strings.Cut(rest, ",")
+		s = strings.TrimSpace(s)
+	}
+	return out
+}
+
+// tokenCachingSource wraps any httpxauth TokenSource and reuses its token
+// for TTL after each successful call. Callers arriving while the cache is
+// cold share a single call to Inner; errors are not cached.
+type tokenCachingSource struct {
+	Inner httpxauth.TokenSource
+	TTL   time.Duration
+
+	group   singleflight.Group
+	mu      sync.RWMutex
+	token   string
+	expires time.Time
+}
+
+func (s *tokenCachingSource) Token(ctx context.Context) (string, error) {
+	if tok, ok := s.cached(); ok {
+		return tok, nil
+	}
+	v, err, _ := s.group.Do("token", func() (any, error) {
+		if tok, ok := s.cached(); ok { // filled while we waited for the group
+			return tok, nil
+		}
+		tok, err := s.Inner.Token(ctx)
+		if err != nil {
+			return "", err
+		}
+		s.mu.Lock()
+		s.token, s.expires = tok, time.Now().Add(s.TTL)
+		s.mu.Unlock()
+		return tok, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return v.(string), nil
+}
+
+func (s *tokenCachingSource) cached() (string, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.token, s.token != "" && time.Now().Before(s.expires)
+}
+
+// tokenSourceFunc adapts a function to the httpxauth TokenSource interface.
+type tokenSourceFunc func(ctx context.Context) (string, error)
+
+func (f tokenSourceFunc) Token(ctx context.Context) (string, error) { return f(ctx) }
+
 type rotatingTokenSource struct {
 	tokens []string
 	idx    *int
@@ -8,16 +8,29 @@
 // - Context support
 // - Form upload (application/x-www-form-urlencoded)
 // - Multipart file upload (multipart/form-data)
+// - Status class helpers (redirect / informational)
+// - Negotiated protocol version (HTTP/1.1 vs HTTP/2)
+// - Full response header map (multi-value, canonical names)
+// - Parallel execution with ordered results and collected errors
+// - Streaming NDJSON / JSON-array decoding
+// - Reusable, cloneable request templates
+// - Form bodies from a plain map
+// - Struct-free JSON inspection (map views of a response)
 package basic
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/n0l3r/httpx"
@@ -56,6 +69,14 @@ func Run() {
 	exampleDefaultHeaders(srv.URL)
 	exampleBodyForm(srv.URL)
 	exampleBodyMultipart(srv.URL)
+	exampleStatusClasses(srv.URL)
+	exampleProtocolVersion()
+	exampleAllHeaders(srv.URL)
+	exampleExecuteAll(srv.URL)
+	exampleEachJSON(srv.URL)
+	exampleRequestTemplate(srv.URL)
+	exampleFormValues(srv.URL)
+	exampleResponseMap(srv.URL)
 }
 
 // --- Examples ---
@@ -170,6 +191,7 @@ func exampleResponseHelpers(baseURL string) {
 
 	resp, _ := c.Get(context.Background(), "/users/1")
 	fmt.Printf("  StatusCode:    %d\n", resp.StatusCode())
+	fmt.Printf("  StatusText:    %s\n", statusText(resp))
 	fmt.Printf("  IsSuccess:     %v\n", resp.IsSuccess())
 	fmt.Printf("  IsClientError: %v\n", resp.IsClientError())
 	fmt.Printf("  IsServerError: %v\n", resp.IsServerError())
@@ -180,6 +202,7 @@ func exampleResponseHelpers(baseURL string) {
 	resp404, _ := c.Get(context.Background(), "/not-found")
 	err := resp404.EnsureSuccess()
 	fmt.Printf("  EnsureSuccess on 404: %v\n", err)
+	fmt.Printf("  StatusText on 404: %q\n", statusText(resp404))
 }
 
 func exampleContextDeadline(baseURL string) {
@@ -261,6 +284,496 @@ func exampleBodyMultipart(baseURL string) {
 	fmt.Printf("  ✓ status=%d  file uploaded as multipart/form-data\n", resp.StatusCode())
 }
 
+func exampleStatusClasses(baseURL string) {
+	fmt.Println("\n[12] Status class helpers — redirect / informational")
+
+	c, _ := httpx.New(httpx.WithBaseURL(baseURL))
+
+	// 304 is never followed, so it reaches the caller as-is.
+	resp, err := c.Get(context.Background(), "/not-modified")
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	fmt.Printf("  ✓ GET /not-modified → %d  IsRedirect=%v\n", resp.StatusCode(), resp.IsRedirect())
+
+	// Response has no 1xx check; boundary values for isInformational.
+	for _, code := range []int{99, 100, 199, 200} {
+		fmt.Printf("    %3d  isInformational=%v\n", code, isInformational(code))
+	}
+}
+
+func exampleProtocolVersion() {
+	fmt.Println("\n[13] Negotiated protocol version — HTTP/2 over TLS")
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	defer srv.Close()
+
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithTransport(srv.Client().Transport), // trusts the test certificate
+	)
+
+	resp, err := c.Get(context.Background(), "/")
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	// The protocol lives on the raw *http.Response.
+	fmt.Printf("  ✓ status=%d proto=%s (major=%d minor=%d)\n",
+		resp.StatusCode(), resp.Raw.Proto, resp.Raw.ProtoMajor, resp.Raw.ProtoMinor)
+	fmt.Printf("    HTTP/2 negotiated: %v\n", resp.Raw.ProtoMajor == 2)
+}
+
+func exampleAllHeaders(baseURL string) {
+	fmt.Println("\n[14] All response headers — independent copy of the header map")
+
+	c, _ := httpx.New(httpx.WithBaseURL(baseURL))
+
+	resp, err := c.Get(context.Background(), "/multi-header")
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	// resp.Header(key) returns a single value; clone the raw map to
+	// iterate everything.
+	headers := resp.Raw.Header.Clone()
+
+	fmt.Printf("  ✓ Set-Cookie values: %q\n", headers.Values("Set-Cookie"))
+	_, canonical := headers["X-Trace-Hop"] // sent as "x-trace-hop"
+	fmt.Printf("    Canonical name X-Trace-Hop: %v\n", canonical)
+
+	headers.Set("X-Trace-Hop", "mutated")
+	fmt.Printf("    Copy is independent: %v\n", resp.Raw.Header.Get("X-Trace-Hop") == "edge-1")
+}
+
+func exampleExecuteAll(baseURL string) {
+	fmt.Println("\n[15] executeAll — parallel requests, results in input order")
+
+	c, _ := httpx.New()
+	ctx := context.Background()
+
+	var reqs []*http.Request
+	for _, id := range []string{"5", "4", "3", "2", "1"} {
+		req, _ := http.NewRequest(http.MethodGet, baseURL+"/items/"+id, nil)
+		reqs = append(reqs, req)
+	}
+	for _, path := range []string{"/unreachable", "/gone"} {
+		broken, _ := http.NewRequest(http.MethodGet, "http://127.0.0.1:1"+path, nil)
+		reqs = append(reqs, broken)
+	}
+
+	start := time.Now()
+	resps, err := executeAll(ctx, c, reqs, 3)
+	fmt.Printf("  ✓ %d requests, concurrency 3, took %v\n", len(reqs), time.Since(start).Round(10*time.Millisecond))
+	for i, resp := range resps {
+		if resp == nil {
+			fmt.Printf("    [%d] failed\n", i)
+			continue
+		}
+		fmt.Printf("    [%d] %d %s\n", i, resp.StatusCode(), strings.TrimSpace(resp.String()))
+	}
+
+	var merr *multiError
+	if errors.As(err, &merr) {
+		fmt.Printf("  ✓ multiError with %d failures:\n", len(merr.Errors()))
+		for _, e := range merr.Errors() {
+			fmt.Printf("    - %v\n", e)
+		}
+	}
+}
+
+// executeAll sends reqs with at most concurrency in flight (all at once when
+// concurrency <= 0). resps[i] answers reqs[i]; a failed request leaves a nil
+// entry without stopping the others. The error is nil when every request
+// succeeds and a *multiError holding each failure otherwise.
+func executeAll(ctx context.Context, c *httpx.Client, reqs []*http.Request, concurrency int) ([]*httpx.Response, error) {
+	if concurrency <= 0 {
+		concurrency = len(reqs)
+	}
+	resps := make([]*httpx.Response, len(reqs))
+	errs := make([]error, len(reqs))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			errs[i] = fmt.Errorf("request %d (%s %s): %w", i, req.Method, req.URL, ctx.Err())
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			resp, err := c.Do(req.WithContext(ctx))
+			if err != nil {
+				errs[i] = fmt.Errorf("request %d (%s %s): %w", i, req.Method, req.URL, err)
+				return
+			}
+			resps[i] = resp
+		}()
+	}
+	wg.Wait()
+
+	var merr multiError
+	for _, err := range errs {
+		if err != nil {
+			merr = append(merr, err)
+		}
+	}
+	if len(merr) == 0 {
+		return resps, nil
+	}
+	return resps, &merr
+}
+
+// multiError collects the failures of a batch. errors.Is and errors.As see
+// every element through Unwrap.
+type multiError []error
+
+// Errors returns the collected errors in request order.
+func (m *multiError) Errors() []error { return *m }
+
+func (m *multiError) Unwrap() []error { return *m }
+
+// Error lists every sub-error, one per line after a count.
+func (m *multiError) Error() string {
+	errs := *m
+	if len(errs) == 1 {
+		return errs[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d errors occurred:", len(errs))
+	for _, err := range errs {
+		b.WriteString("\n\t* ")
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func exampleEachJSON(baseURL string) {
+	fmt.Println("\n[16] eachJSON — decode a stream one value at a time")
+
+	type Event struct {
+		Seq  int    `json:"seq"`
+		Kind string `json:"kind"`
+	}
+
+	c, _ := httpx.New(httpx.WithBaseURL(baseURL))
+	req, _ := c.NewRequest(context.Background(), http.MethodGet, "/events").Accept("application/x-ndjson").Build()
+
+	// httpx reads the whole body into the Response; send the built request
+	// over a plain http.Client to consume the stream as it arrives.
+	raw, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	defer raw.Body.Close()
+
+	var seqs []int
+	err = eachJSON(raw.Body, func(msg json.RawMessage) error {
+		var ev Event
+		if err := json.Unmarshal(msg, &ev); err != nil {
+			return err
+		}
+		seqs = append(seqs, ev.Seq)
+		return nil
+	})
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	fmt.Printf("  ✓ NDJSON: %d events in order %v\n", len(seqs), seqs)
+
+	// A JSON array decodes the same way; returning an error stops early.
+	errEnough := errors.New("enough")
+	var first []string
+	err = eachJSON(strings.NewReader(`[{"kind":"a"}, {"kind":"b"}, {"kind":"c"}]`), func(msg json.RawMessage) error {
+		var ev Event
+		json.Unmarshal(msg, &ev)
+		first = append(first, ev.Kind)
+		if len(first) == 2 {
+			return errEnough
+		}
+		return nil
+	})
+	fmt.Printf("  ✓ JSON array, stopped after %v: %v\n", first, errors.Is(err, errEnough))
+}
+
+// eachJSON calls fn for each value in r, which holds either a JSON array or
+// newline-delimited JSON, decoding one value at a time. It stops at the
+// first error from fn or the decoder.
+func eachJSON(r io.Reader, fn func(json.RawMessage) error) error {
+	br := bufio.NewReader(r)
+	isArray := false
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\n' || b[0] == '\r' {
+			br.ReadByte()
+			continue
+		}
+		isArray = b[0] == '['
+		break
+	}
+
+	dec := json.NewDecoder(br)
+	if isArray {
+		if _, err := dec.Token(); err != nil { // [
+			return err
+		}
+	}
+	for {
+		if isArray && !dec.More() {
+			_, err := dec.Token() // ]
+			return err
+		}
+		var msg json.RawMessage
+		if err := dec.Decode(&msg); err != nil {
+			if !isArray && err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func exampleRequestTemplate(baseURL string) {
+	fmt.Println("\n[17] requestTemplate.Clone — shared auth and headers, independent requests")
+
+	c, _ := httpx.New(httpx.WithBaseURL(baseURL))
+	ctx := context.Background()
+
+	base := newRequestTemplate().
+		BearerToken("batch-token").
+		Header("X-Client", "batch-job").
+		Accept("application/json")
+
+	for _, id := range []string{"a1", "b2"} {
+		req, err := base.Clone().Query("fields", "id").Build(c.NewRequest(ctx, http.MethodGet, "/items/"+id))
+		if err != nil {
+			fmt.Printf("  ✗ build: %v\n", err)
+			return
+		}
+		resp, err := c.Do(req)
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			return
+		}
+		fmt.Printf("  ✓ %s → %d %s\n", req.URL.RequestURI(), resp.StatusCode(), strings.TrimSpace(resp.String()))
+		fmt.Printf("    Authorization: %s, X-Client: %s\n", req.Header.Get("Authorization"), req.Header.Get("X-Client"))
+	}
+
+	admin := base.Clone().BasicAuth("admin", "s3cret").Header("X-Client", "admin-tool").
+		ContentType("application/merge-patch+json")
+	adminReq, _ := admin.Build(c.NewRequest(ctx, http.MethodGet, "/users"))
+	baseReq, _ := base.Build(c.NewRequest(ctx, http.MethodGet, "/users"))
+	fmt.Printf("  ✓ clone changed:     X-Client=%s, basic auth=%v, Content-Type=%s\n", adminReq.Header.Get("X-Client"),
+		strings.HasPrefix(adminReq.Header.Get("Authorization"), "Basic "), adminReq.Header.Get("Content-Type"))
+	fmt.Printf("  ✓ original unchanged: X-Client=%s, query=%q, Content-Type=%q\n", baseReq.Header.Get("X-Client"),
+		baseReq.URL.RawQuery, baseReq.Header.Get("Content-Type"))
+}
+
+// requestTemplate holds the builder settings shared by a batch of requests:
+// headers, query parameters and auth. httpx.RequestBuilder can't be copied,
+// so a template is applied to a fresh builder per request with Build, and
+// Clone gives a variant that doesn't touch the original.
+type requestTemplate struct {
+	header      http.Header
+	query       url.Values
+	contentType string
+	bearer      string
+	basic       *[2]string // username, password
+}
+
+func newRequestTemplate() *requestTemplate {
+	return &requestTemplate{header: http.Header{}, query: url.Values{}}
+}
+
+// Header sets a header, replacing any earlier value, as RequestBuilder.Header
+// does.
+func (t *requestTemplate) Header(key, value string) *requestTemplate {
+	t.header.Set(key, value)
+	return t
+}
+
+// Query adds a query parameter.
+func (t *requestTemplate) Query(key, value string) *requestTemplate {
+	t.query.Add(key, value)
+	return t
+}
+
+// Accept sets the Accept header.
+func (t *requestTemplate) Accept(contentType string) *requestTemplate {
+	return t.Header("Accept", contentType)
+}
+
+// ContentType sets the Content-Type header. Build applies it last, so it
+// wins over one set by a body option on the builder.
+func (t *requestTemplate) ContentType(contentType string) *requestTemplate {
+	t.contentType = contentType
+	return t
+}
+
+// BearerToken sets bearer auth, replacing basic auth.
+func (t *requestTemplate) BearerToken(token string) *requestTemplate {
+	t.bearer, t.basic = token, nil
+	return t
+}
+
+// BasicAuth sets basic auth, replacing a bearer token.
+func (t *requestTemplate) BasicAuth(username, password string) *requestTemplate {
+	t.bearer, t.basic = "", &[2]string{username, password}
+	return t
+}
+
+// Clone returns a deep copy; changing either template leaves the other as
+// it was.
+func (t *requestTemplate) Clone() *requestTemplate {
+	clone := &requestTemplate{header: t.header.Clone(), query: url.Values{}, contentType: t.contentType, bearer: t.bearer}
+	for key, values := range t.query {
+		clone.query[key] = slices.Clone(values)
+	}
+	if t.basic != nil {
+		basic := *t.basic
+		clone.basic = &basic
+	}
+	return clone
+}
+
+// Build applies the template to b, which carries the request's own context,
+// method and path, and builds the request.
+func (t *requestTemplate) Build(b *httpx.RequestBuilder) (*http.Request, error) {
+	for key, values := range t.header {
+		for _, v := range values {
+			b = b.Header(key, v)
+		}
+	}
+	b = b.QueryValues(t.query) // keeps every value, unlike Query
+	if t.contentType != "" {
+		b = b.ContentType(t.contentType)
+	}
+	switch {
+	case t.bearer != "":
+		b = b.BearerToken(t.bearer)
+	case t.basic != nil:
+		b = b.BasicAuth(t.basic[0], t.basic[1])
+	}
+	return b.Build()
+}
+
+func exampleFormValues(baseURL string) {
+	fmt.Println("\n[18] formValues — map[string]string to a form body")
+
+	c, _ := httpx.New(httpx.WithBaseURL(baseURL))
+
+	for _, pairs := range []map[string]string{
+		{"username": "alice", "remember": "true"},
+		{}, // an empty map is an empty, valid body
+	} {
+		resp, err := c.Post(context.Background(), "/form-echo", httpx.WithFormBody(formValues(pairs)))
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			return
+		}
+		var echo struct {
+			ContentType string            `json:"content_type"`
+			Form        map[string]string `json:"form"`
+		}
+		if err := resp.JSON(&echo); err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			return
+		}
+		fmt.Printf("  ✓ %d fields sent → %d %s, server parsed %v\n", len(pairs), resp.StatusCode(), echo.ContentType, echo.Form)
+	}
+}
+
+// formValues converts a flat map to url.Values for httpx.WithFormBody. A nil
+// or empty map gives an empty body.
+func formValues(pairs map[string]string) url.Values {
+	values := make(url.Values, len(pairs))
+	for key, value := range pairs {
+		values.Set(key, value)
+	}
+	return values
+}
+
+func exampleResponseMap(baseURL string) {
+	fmt.Println("\n[19] responseMap / responseStringMap — JSON without a struct")
+
+	c, _ := httpx.New(httpx.WithBaseURL(baseURL))
+
+	resp, err := c.Get(context.Background(), "/profile")
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	m, err := responseMap(resp)
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	address, _ := m["address"].(map[string]any)
+	tags, _ := m["tags"].([]any)
+	fmt.Printf("  ✓ name=%v, address.city=%v, %d tags %v\n", m["name"], address["city"], len(tags), tags)
+
+	headers, _ := c.Get(context.Background(), "/echo-headers")
+	sm, err := responseStringMap(headers)
+	fmt.Printf("  ✓ all-string object: %d keys, User-Agent=%q, err=%v\n", len(sm), sm["User-Agent"], err)
+
+	_, err = responseStringMap(resp)
+	fmt.Printf("  ✓ mixed object rejected: %v\n", err)
+}
+
+// responseMap decodes a JSON object body into a map. Nested objects come
+// back as map[string]any, arrays as []any and numbers as float64.
+func responseMap(resp *httpx.Response) (map[string]any, error) {
+	var m map[string]any
+	if err := json.Unmarshal(resp.Bytes(), &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// responseStringMap decodes a JSON object whose values are all strings. Any
+// other value type is an error naming the key.
+func responseStringMap(resp *httpx.Response) (map[string]string, error) {
+	m, err := responseMap(resp)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]string, len(m))
+	for key, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("json: value of %q is %T, not a string", key, v)
+		}
+		out[key] = s
+	}
+	return out, nil
+}
+
+// statusText returns the standard reason phrase for resp's status, e.g.
+// "Not Found", or "" for an unknown code.
+func statusText(resp *httpx.Response) string { return http.StatusText(resp.StatusCode()) }
+
+// isInformational reports whether code is a 1xx status.
+func isInformational(code int) bool { return code >= 100 && code < 200 }
+
 // --- Embedded test server ---
 
 func startServer() *httptest.Server {
@@ -297,10 +810,45 @@ func startServer() *httptest.Server {
 		}
 	})
 
+	mux.HandleFunc("/items/", func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/items/")
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":%q}`, id)
+	})
+
+	mux.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		flusher, _ := w.(http.Flusher)
+		for i := 1; i <= 10; i++ {
+			fmt.Fprintf(w, "{\"seq\":%d,\"kind\":\"tick\"}\n", i)
+			if flusher != nil {
+				flusher.Flush()
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	})
+
+	mux.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":1,"name":"Alice","address":{"city":"Berlin","zip":"10115"},"tags":["admin","beta"]}`)
+	})
+
 	mux.HandleFunc("/not-found", func(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
 	})
 
+	mux.HandleFunc("/not-modified", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	})
+
+	mux.HandleFunc("/multi-header", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Set-Cookie", "session=abc")
+		w.Header().Add("Set-Cookie", "theme=dark")
+		w.Header()["x-trace-hop"] = []string{"edge-1"} // non-canonical on purpose
+		w.WriteHeader(http.StatusOK)
+	})
+
 	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
 		select {
 		case <-r.Context().Done():
@@ -328,6 +876,19 @@ func startServer() *httptest.Server {
 		w.WriteHeader(http.StatusOK)
 	})
 
+	mux.HandleFunc("/form-echo", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		form := make(map[string]string)
+		for key := range r.PostForm {
+			form[key] = r.FormValue(key)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"content_type": r.Header.Get("Content-Type"), "form": form})
+	})
+
 	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
 		if err := r.ParseMultipartForm(1 << 20); err != nil {
 			http.Error(w, err.Error(), http.StatusBadRequest)
@@ -3,18 +3,35 @@
 // - NoopCache (disable caching)
 // - TieredCache (L1 memory + L2 any backend)
 // - Custom cache key / invalidation
+// - Cache middleware with pluggable backends (memory, Redis)
+// - Cache-Control awareness (no-store, no-cache, max-age)
+// - Conditional requests (ETag / If-None-Match / 304)
+// - Cache statistics (hits, misses, evictions, entries)
+// - Per-request cache bypass via context
+// - Custom cache key function
+// - Cache warming with bounded concurrency
+// - stale-while-revalidate
 package cache
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/n0l3r/httpx"
 	"github.com/n0l3r/httpx/cache/tiered"
+	"github.com/redis/go-redis/v9"
 )
 
 // Run executes all cache examples.
@@ -30,6 +47,14 @@ func Run() {
 	exampleCacheTTLExpiry()
 	exampleCacheInvalidation()
 	exampleCacheOnlyGet()
+	exampleRedisBackend()
+	exampleCacheControl()
+	exampleConditionalRequests()
+	exampleCacheStats()
+	exampleCacheBypass()
+	exampleCacheKeyFunc()
+	exampleCacheWarm()
+	exampleStaleWhileRevalidate()
 }
 
 func countingServer() (*httptest.Server, *atomic.Int32) {
@@ -181,3 +206,831 @@ func exampleCacheOnlyGet() {
 	}
 	fmt.Printf("  ✓ 3 POST requests → server called %d time(s) (POST never cached)\n", calls.Load())
 }
+
+// [8] Redis backend — one cache shared by every client instance.
+func exampleRedisBackend() {
+	fmt.Println("\n[8] Redis-backed cache — shared across instances")
+
+	// miniredis is an in-process Redis server, so no external service is needed.
+	mr, err := miniredis.Run()
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	srv, calls := countingServer()
+	defer srv.Close()
+
+	// Each client stands in for one replica of a horizontally scaled service.
+	replica := func(b cacheBackend) *httpx.Client {
+		c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(cacheMiddleware(b, time.Minute)))
+		return c
+	}
+
+	// Process-local: each replica has its own memory, so both miss.
+	replicaA, replicaB := replica(&memoryBackend{}), replica(&memoryBackend{})
+	replicaA.Get(context.Background(), "/catalog")
+	replicaB.Get(context.Background(), "/catalog")
+	fmt.Printf("  → memory backend (one per replica): server called %d time(s)\n", calls.Load())
+
+	// Shared: replica B hits the entry replica A stored in Redis.
+	calls.Store(0)
+	backend := &redisBackend{rdb: rdb, prefix: "httpx:"}
+	replicaA, replicaB = replica(backend), replica(backend)
+	replicaA.Get(context.Background(), "/catalog")
+	resp, _ := replicaB.Get(context.Background(), "/catalog")
+	fmt.Printf("  ✓ redis backend (shared): server called %d time(s)\n", calls.Load())
+	fmt.Printf("    Body from Redis: %s\n", resp.String())
+	fmt.Printf("    Redis keys: %v  TTL: %v\n", mr.Keys(), mr.TTL("httpx:"+srv.URL+"/catalog"))
+
+	// Flush drops every entry under the prefix.
+	if err := backend.Flush(context.Background()); err != nil {
+		fmt.Printf("  ✗ flush: %v\n", err)
+		return
+	}
+	replicaB.Get(context.Background(), "/catalog")
+	fmt.Printf("  ✓ after Flush → server called %d time(s)\n", calls.Load())
+}
+
+// [9] Cache-Control — the server (and the caller) decide what is cached.
+func exampleCacheControl() {
+	fmt.Println("\n[9] Cache-Control — no-store, max-age, request no-cache")
+
+	var calls sync.Map // path → *atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := calls.LoadOrStore(r.URL.Path, new(atomic.Int32))
+		n.(*atomic.Int32).Add(1)
+		switch r.URL.Path {
+		case "/no-store":
+			w.Header().Set("Cache-Control", "no-store")
+		case "/max-age":
+			w.Header().Set("Cache-Control", "public, max-age=1")
+		}
+		fmt.Fprintf(w, `{"path":%q}`, r.URL.Path)
+	}))
+	defer srv.Close()
+	count := func(path string) int32 {
+		n, _ := calls.LoadOrStore(path, new(atomic.Int32))
+		return n.(*atomic.Int32).Load()
+	}
+
+	// Default TTL is one minute; the response headers override it.
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(cacheMiddleware(&memoryBackend{}, time.Minute)),
+	)
+	ctx := context.Background()
+
+	c.Get(ctx, "/no-store")
+	c.Get(ctx, "/no-store")
+	fmt.Printf("  ✓ no-store:  2 requests → server called %d time(s)\n", count("/no-store"))
+
+	c.Get(ctx, "/max-age")
+	c.Get(ctx, "/max-age")
+	fmt.Printf("  ✓ max-age=1: 2 requests → server called %d time(s)\n", count("/max-age"))
+	time.Sleep(1100 * time.Millisecond)
+	c.Get(ctx, "/max-age")
+	fmt.Printf("    after 1.1s (max-age, not the 1m default) → server called %d time(s)\n", count("/max-age"))
+
+	c.Get(ctx, "/plain") // cached with the default TTL
+	req, _ := c.NewRequest(ctx, "GET", "/plain").Header("Cache-Control", "no-cache").Build()
+	c.Do(req)
+	fmt.Printf("  ✓ request no-cache bypasses a fresh entry → server called %d time(s)\n", count("/plain"))
+}
+
+// [10] Conditional requests — revalidate with ETag instead of re-downloading.
+func exampleConditionalRequests() {
+	fmt.Println("\n[10] Conditional GET — ETag / If-None-Match / 304 Not Modified")
+
+	var (
+		version     atomic.Int32
+		full, notMo atomic.Int32
+		lastINM     atomic.Value
+	)
+	version.Store(1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := fmt.Sprintf(`"v%d"`, version.Load())
+		lastINM.Store(r.Header.Get("If-None-Match"))
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache") // store, but revalidate every time
+		if r.Header.Get("If-None-Match") == etag {
+			notMo.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		fmt.Fprintf(w, `{"config":"version %d"}`, version.Load())
+	}))
+	defer srv.Close()
+
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(cacheMiddleware(&memoryBackend{}, time.Minute, withConditionalRequests(true))),
+	)
+	ctx := context.Background()
+
+	for i := range 3 {
+		resp, _ := c.Get(ctx, "/config")
+		fmt.Printf("  request %d → %d %s  (If-None-Match sent: %q)\n",
+			i+1, resp.StatusCode(), resp.String(), lastINM.Load())
+	}
+	fmt.Printf("  ✓ full responses=%d  304s=%d\n", full.Load(), notMo.Load())
+
+	version.Store(2)
+	resp, _ := c.Get(ctx, "/config")
+	fmt.Printf("  ✓ after change → %s\n", resp.String())
+	resp, _ = c.Get(ctx, "/config")
+	fmt.Printf("    next 304 keeps the new body → %s\n", resp.String())
+}
+
+// [11] Cache statistics — observe how effective the cache is.
+func exampleCacheStats() {
+	fmt.Println("\n[11] Cache stats — hits, misses, evictions, entries")
+
+	srv, calls := countingServer()
+	defer srv.Close()
+
+	backend := &memoryBackend{}
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(cacheMiddleware(backend, 50*time.Millisecond)),
+	)
+	ctx := context.Background()
+
+	for _, p := range []string{"/a", "/a", "/b", "/a", "/b", "/c"} {
+		c.Get(ctx, p)
+	}
+	fmt.Printf("  after a,a,b,a,b,c:  %+v  (server calls=%d)\n", backend.Stats(), calls.Load())
+
+	time.Sleep(60 * time.Millisecond) // let every entry expire
+	c.Get(ctx, "/a")
+	fmt.Printf("  after TTL expiry:   %+v\n", backend.Stats())
+
+	// Counters are atomic, so concurrent readers are counted exactly.
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Get(ctx, "/a")
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("  ✓ after 50 concurrent hits: %+v\n", backend.Stats())
+}
+
+// [12] Per-request cache bypass — force a fresh response for one call.
+func exampleCacheBypass() {
+	fmt.Println("\n[12] Cache bypass — skip read and write for one request")
+
+	srv, calls := countingServer()
+	defer srv.Close()
+
+	backend := &memoryBackend{}
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(cacheMiddleware(backend, time.Minute)),
+	)
+	ctx := context.Background()
+
+	c.Get(ctx, "/prices")
+	resp, _ := c.Get(ctx, "/prices")
+	fmt.Printf("  → cached:   server calls=%d body=%s\n", calls.Load(), resp.String())
+
+	resp, _ = c.Get(withCacheBypass(ctx), "/prices")
+	fmt.Printf("  ✓ bypassed: server calls=%d body=%s\n", calls.Load(), resp.String())
+
+	// The bypassed response was not written back: the old entry still serves.
+	resp, _ = c.Get(ctx, "/prices")
+	fmt.Printf("    next cached read: server calls=%d body=%s\n", calls.Load(), resp.String())
+}
+
+// [13] Custom cache key — ignore volatile params, vary on Accept-Language.
+func exampleCacheKeyFunc() {
+	fmt.Println("\n[13] Cache key function — ignore ts/api_key, vary on Accept-Language")
+
+	srv, calls := countingServer()
+	defer srv.Close()
+
+	keyFn := func(req *http.Request) string {
+		u := *req.URL
+		q := u.Query()
+		q.Del("ts")
+		q.Del("api_key")
+		u.RawQuery = q.Encode()
+		return u.String() + " lang=" + req.Header.Get("Accept-Language")
+	}
+
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(cacheMiddleware(&memoryBackend{}, time.Minute, withCacheKeyFunc(keyFn))),
+	)
+	ctx := context.Background()
+
+	c.Get(ctx, "/news?page=1&ts=1700000000&api_key=k1")
+	c.Get(ctx, "/news?page=1&ts=1700000042&api_key=k2")
+	fmt.Printf("  ✓ different ts/api_key → server called %d time(s) (shared entry)\n", calls.Load())
+
+	for _, lang := range []string{"en", "id", "en"} {
+		req, _ := c.NewRequest(ctx, "GET", "/news?page=2").Header("Accept-Language", lang).Build()
+		c.Do(req)
+	}
+	fmt.Printf("  ✓ Accept-Language en, id, en → server called %d time(s) in total\n", calls.Load())
+}
+
+// [14] Cache warming — pre-populate before taking traffic.
+func exampleCacheWarm() {
+	fmt.Println("\n[14] Cache warming — concurrent pre-fetch with error aggregation")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/broken":
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		case "/slow":
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+		fmt.Fprintf(w, `{"path":%q}`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	backend := &memoryBackend{}
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(cacheMiddleware(backend, time.Minute)),
+	)
+	ctx := context.Background()
+
+	paths := []string{"/home", "/pricing", "/docs", "/broken", "/blog"}
+	err := warmCache(ctx, c, paths, 3)
+	fmt.Printf("  ✓ warmed %d paths, entries=%d, server calls=%d\n",
+		len(paths), backend.Stats().CurrentEntries, calls.Load())
+	fmt.Printf("    aggregated errors: %v\n", err)
+
+	calls.Store(0)
+	for _, p := range paths {
+		c.Get(ctx, p)
+	}
+	fmt.Printf("  ✓ serving traffic: server calls=%d (only the failed path)\n", calls.Load())
+
+	// Cancellation stops in-flight requests and skips the rest.
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	err = warmCache(ctx, c, []string{"/slow", "/slow?v=2", "/slow?v=3"}, 1)
+	fmt.Printf("  ✓ cancelled warm: %v\n", err)
+}
+
+// [15] stale-while-revalidate — answer instantly, refresh in the background.
+func exampleStaleWhileRevalidate() {
+	fmt.Println("\n[15] stale-while-revalidate — TTL 100ms, stale window 1s")
+
+	var version atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond) // slow origin
+		fmt.Fprintf(w, `{"version":%d}`, version.Add(1))
+	}))
+	defer srv.Close()
+
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(cacheMiddleware(&memoryBackend{}, 100*time.Millisecond,
+			withStaleWhileRevalidate(time.Second))),
+	)
+	ctx := context.Background()
+
+	timed := func(label string) {
+		start := time.Now()
+		resp, err := c.Get(ctx, "/rates")
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			return
+		}
+		fmt.Printf("  %-28s %s in %v\n", label, resp.String(), time.Since(start).Round(10*time.Millisecond))
+	}
+
+	timed("cold (origin):")
+	time.Sleep(150 * time.Millisecond) // entry is now stale
+	timed("stale (served instantly):")
+	time.Sleep(150 * time.Millisecond) // background refresh has landed
+	timed("refreshed (from cache):")
+	fmt.Printf("  ✓ origin calls=%d\n", version.Load())
+}
+
+// ---
+
+// warmCache fetches every path through c — and therefore through its cache
+// middleware — with at most concurrency requests in flight. A failed path
+// does not abort the others; all failures are joined into one error.
+// Cancelling ctx stops in-flight requests and skips paths not yet started.
+func warmCache(ctx context.Context, c *httpx.Client, paths []string, concurrency int) error {
+	var (
+		mu   sync.Mutex
+		errs []error
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, max(concurrency, 1))
+	)
+	record := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	for _, p := range paths {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			record(fmt.Errorf("warm %s: %w", p, ctx.Err()))
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			resp, err := c.Get(ctx, p)
+			switch {
+			case err != nil:
+				record(fmt.Errorf("warm %s: %w", p, err))
+			case !resp.IsSuccess():
+				record(fmt.Errorf("warm %s: status %d", p, resp.StatusCode()))
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// cacheBackend stores serialized responses for cacheMiddleware.
+// Implementations must be safe for concurrent use.
+type cacheBackend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	Flush(ctx context.Context) error
+}
+
+// validatorBackend is implemented by backends that keep ETag validators
+// apart from cached responses, so no cache key can collide with one. Other
+// backends get them in their shared keyspace under validatorKey.
+type validatorBackend interface {
+	GetValidator(ctx context.Context, key string) ([]byte, bool, error)
+	SetValidator(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// cacheOption configures optional cacheMiddleware behavior.
+type cacheOption func(*cachingTransport)
+
+// withConditionalRequests keeps responses that carry an ETag as validators.
+// Once an entry is no longer fresh, the request is sent with If-None-Match
+// and a 304 Not Modified is answered from the stored response.
+func withConditionalRequests(enabled bool) cacheOption {
+	return func(t *cachingTransport) { t.conditional = enabled }
+}
+
+type cacheBypassKey struct{}
+
+// withCacheBypass returns a context whose requests skip cacheMiddleware
+// entirely — no lookup and no store. Being a context value, it passes
+// through any other middleware untouched.
+func withCacheBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// withCacheKeyFunc replaces the default cache key (the full request URL).
+func withCacheKeyFunc(fn func(*http.Request) string) cacheOption {
+	return func(t *cachingTransport) { t.keyFunc = fn }
+}
+
+// withStaleWhileRevalidate serves an expired entry for up to d past its TTL
+// while refreshing it in the background (RFC 5861).
+func withStaleWhileRevalidate(d time.Duration) cacheOption {
+	return func(t *cachingTransport) { t.staleWindow = d }
+}
+
+// validatorTTL is how long an ETag validator outlives its fresh entry.
+const validatorTTL = 24 * time.Hour
+
+// cacheMiddleware caches successful GET responses in backend for ttl.
+// Backend errors are treated as misses so the cache never fails a request.
+//
+// Cache-Control is honored on both sides: a request with no-cache skips the
+// lookup, and a response with no-store or no-cache is not stored, while
+// max-age=N replaces ttl for that entry.
+func cacheMiddleware(backend cacheBackend, ttl time.Duration, opts ...cacheOption) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		t := &cachingTransport{next: next, backend: backend, ttl: ttl, keyFunc: defaultCacheKey}
+		for _, opt := range opts {
+			opt(t)
+		}
+		return t
+	}
+}
+
+// cachingTransport is the http.RoundTripper installed by cacheMiddleware.
+type cachingTransport struct {
+	next        http.RoundTripper
+	backend     cacheBackend
+	ttl         time.Duration
+	keyFunc     func(*http.Request) string
+	conditional bool
+	staleWindow time.Duration
+
+	refreshing sync.Map // key → struct{} while a background refresh runs
+}
+
+// defaultCacheKey keys entries by the full request URL.
+func defaultCacheKey(req *http.Request) string { return req.URL.String() }
+
+func (t *cachingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Context().Value(cacheBypassKey{}) != nil {
+		return t.next.RoundTrip(req)
+	}
+
+	ctx := req.Context()
+	key := t.keyFunc(req)
+	if _, noCache := cacheDirectives(req.Header)["no-cache"]; !noCache {
+		if wire, freshUntil, ok := t.load(ctx, key); ok {
+			fresh := time.Now().Before(freshUntil)
+			if fresh || t.staleWindow > 0 {
+				if resp, err := decodeResponse(wire, req); err == nil {
+					if !fresh {
+						t.revalidate(key, req)
+					}
+					return resp, nil
+				}
+			}
+		}
+	}
+
+	// Revalidate a stored response instead of downloading it again.
+	var validator []byte
+	if t.conditional {
+		if wire, ok := t.loadValidator(ctx, key); ok {
+			if stored, err := decodeResponse(wire, req); err == nil {
+				if etag := stored.Header.Get("ETag"); etag != "" {
+					validator = wire
+					req = req.Clone(ctx)
+					req.Header.Set("If-None-Match", etag)
+				}
+			}
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && validator != nil {
+		// The stored body is still valid: answer from it and never let the
+		// empty 304 overwrite it.
+		resp.Body.Close()
+		if entryTTL, ok := responseTTL(resp.Header, t.ttl); ok {
+			t.store(ctx, key, validator, entryTTL)
+		}
+		return decodeResponse(validator, req)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return resp, nil
+	}
+
+	entryTTL, cacheable := responseTTL(resp.Header, t.ttl)
+	keepValidator := t.conditional && resp.Header.Get("ETag") != ""
+	if !cacheable && !keepValidator {
+		return resp, nil
+	}
+	wire, err := encodeResponse(resp)
+	if err != nil {
+		return nil, err
+	}
+	if cacheable {
+		t.store(ctx, key, wire, entryTTL)
+	}
+	if keepValidator {
+		t.storeValidator(ctx, key, wire)
+	}
+	return resp, nil
+}
+
+// store saves wire under key, fresh for ttl. The backend keeps it for an
+// extra staleWindow so it can still be served while being revalidated.
+func (t *cachingTransport) store(ctx context.Context, key string, wire []byte, ttl time.Duration) {
+	_ = t.backend.Set(ctx, key, encodeEntry(wire, time.Now().Add(ttl)), ttl+t.staleWindow)
+}
+
+// load returns the stored response under key and when it stops being fresh.
+func (t *cachingTransport) load(ctx context.Context, key string) ([]byte, time.Time, bool) {
+	b, ok, err := t.backend.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, time.Time{}, false
+	}
+	wire, freshUntil, err := decodeEntry(b)
+	if err != nil {
+		return nil, time.Time{}, false
+	}
+	return wire, freshUntil, true
+}
+
+// loadValidator returns the ETag validator stored for key.
+func (t *cachingTransport) loadValidator(ctx context.Context, key string) ([]byte, bool) {
+	vb, ok := t.backend.(validatorBackend)
+	if !ok {
+		wire, _, ok := t.load(ctx, validatorKey(key))
+		return wire, ok
+	}
+	b, ok, err := vb.GetValidator(ctx, key)
+	if err != nil || !ok {
+		return nil, false
+	}
+	wire, _, err := decodeEntry(b)
+	return wire, err == nil
+}
+
+// storeValidator keeps wire as the ETag validator for key.
+func (t *cachingTransport) storeValidator(ctx context.Context, key string, wire []byte) {
+	entry := encodeEntry(wire, time.Time{})
+	if vb, ok := t.backend.(validatorBackend); ok {
+		_ = vb.SetValidator(ctx, key, entry, validatorTTL)
+		return
+	}
+	_ = t.backend.Set(ctx, validatorKey(key), entry, validatorTTL)
+}
+
+// revalidate refreshes key in the background through the same transport
+// chain, at most once at a time per key. The caller has already been
+// answered with the stale entry.
+func (t *cachingTransport) revalidate(key string, req *http.Request) {
+	if _, busy := t.refreshing.LoadOrStore(key, struct{}{}); busy {
+		return
+	}
+	ctx := context.WithoutCancel(req.Context())
+	bg := req.Clone(ctx)
+	go func() {
+		defer t.refreshing.Delete(key)
+		resp, err := t.next.RoundTrip(bg)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return
+		}
+		if entryTTL, ok := responseTTL(resp.Header, t.ttl); ok {
+			if wire, err := encodeResponse(resp); err == nil {
+				t.store(ctx, key, wire, entryTTL)
+			}
+		}
+	}()
+}
+
+// validatorPrefix marks the keys ETag validators are stored under in
+// backends that are not a validatorBackend.
+const validatorPrefix = "etag:"
+
+// validatorKey is where the ETag validator for key is stored.
+func validatorKey(key string) string { return validatorPrefix + key }
+
+// cacheDirectives parses Cache-Control into directive → value. Flag
+// directives such as no-store map to "".
+func cacheDirectives(h http.Header) map[string]string {
+	out := make(map[string]string)
+	for _, v := range h.Values("Cache-Control") {
+		for _, part := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if name != "" {
+				out[strings.ToLower(name)] = strings.Trim(value, `"`)
+			}
+		}
+	}
+	return out
+}
+
+// responseTTL applies the response Cache-Control to the default ttl.
+// It reports false when the response must not be stored.
+func responseTTL(h http.Header, ttl time.Duration) (time.Duration, bool) {
+	cc := cacheDirectives(h)
+	if _, ok := cc["no-store"]; ok {
+		return 0, false
+	}
+	if _, ok := cc["no-cache"]; ok {
+		return 0, false
+	}
+	if v, ok := cc["max-age"]; ok {
+		secs, err := strconv.Atoi(v)
+		if err != nil || secs <= 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	return ttl, true
+}
+
+// encodeResponse serializes resp in HTTP wire format (status line, headers,
+// body). resp.Body is restored so the caller can still read it.
+func encodeResponse(resp *http.Response) ([]byte, error) {
+	return httputil.DumpResponse(resp, true)
+}
+
+// decodeResponse rebuilds a response serialized by encodeResponse.
+func decodeResponse(b []byte, req *http.Request) (*http.Response, error) {
+	return http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), req)
+}
+
+// encodeEntry prefixes a serialized response with the time it stops being
+// fresh, as a Unix nanosecond timestamp on its own line.
+func encodeEntry(wire []byte, freshUntil time.Time) []byte {
+	var unixNano int64
+	if !freshUntil.IsZero() {
+		unixNano = freshUntil.UnixNano()
+	}
+	return append(strconv.AppendInt(nil, unixNano, 10), append([]byte{'\n'}, wire...)...)
+}
+
+// decodeEntry splits an entry produced by encodeEntry.
+func decodeEntry(b []byte) ([]byte, time.Time, error) {
+	head, wire, ok := bytes.Cut(b, []byte{'\n'})
+	if !ok {
+		return nil, time.Time{}, errors.New("cache: malformed entry")
+	}
+	unixNano, err := strconv.ParseInt(string(head), 10, 64)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("cache: malformed entry: %w", err)
+	}
+	return wire, time.Unix(0, unixNano), nil
+}
+
+// memoryBackendSweepInterval is how often Set drops every expired entry, so
+// keys that are never read again do not pile up.
+const memoryBackendSweepInterval = time.Minute
+
+// memoryBackend is a process-local cacheBackend. ETag validators live in a
+// map of their own and are left out of the counters, which describe cached
+// responses.
+type memoryBackend struct {
+	mu         sync.Mutex
+	entries    map[string]memoryEntry
+	validators map[string]memoryEntry
+	lastSweep  time.Time
+
+	hits, misses, evictions atomic.Int64
+}
+
+// cacheStats is a point-in-time snapshot of memoryBackend counters.
+type cacheStats struct {
+	Hits           int64
+	Misses         int64
+	Evictions      int64
+	CurrentEntries int
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func (m *memoryBackend) Get(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if ok && time.Now().After(e.expiresAt) {
+		delete(m.entries, key)
+		m.evictions.Add(1)
+		ok = false
+	}
+	if !ok {
+		m.misses.Add(1)
+		return nil, false, nil
+	}
+	m.hits.Add(1)
+	return e.value, true, nil
+}
+
+// Stats returns the current counters. Expired entries are evicted on the
+// next Get for their key or by the sweep in Set, whichever comes first.
+func (m *memoryBackend) Stats() cacheStats {
+	m.mu.Lock()
+	entries := len(m.entries)
+	m.mu.Unlock()
+	return cacheStats{
+		Hits:           m.hits.Load(),
+		Misses:         m.misses.Load(),
+		Evictions:      m.evictions.Load(),
+		CurrentEntries: entries,
+	}
+}
+
+func (m *memoryBackend) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.entries == nil {
+		m.entries = make(map[string]memoryEntry)
+	}
+	m.entries[key] = memoryEntry{value: value, expiresAt: time.Now().Add(ttl)}
+	m.sweep()
+	return nil
+}
+
+func (m *memoryBackend) GetValidator(_ context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.validators[key]
+	if !ok || time.Now().After(e.expiresAt) {
+		delete(m.validators, key)
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (m *memoryBackend) SetValidator(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.validators == nil {
+		m.validators = make(map[string]memoryEntry)
+	}
+	m.validators[key] = memoryEntry{value: value, expiresAt: time.Now().Add(ttl)}
+	m.sweep()
+	return nil
+}
+
+// sweep drops expired entries and validators, at most once per
+// memoryBackendSweepInterval. m.mu must be held.
+func (m *memoryBackend) sweep() {
+	now := time.Now()
+	if now.Sub(m.lastSweep) < memoryBackendSweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, e := range m.entries {
+		if now.After(e.expiresAt) {
+			delete(m.entries, key)
+			m.evictions.Add(1)
+		}
+	}
+	for key, e := range m.validators {
+		if now.After(e.expiresAt) {
+			delete(m.validators, key)
+		}
+	}
+}
+
+// Delete removes the cached response for key; its validator, if any, stays
+// so the next request can still revalidate.
+func (m *memoryBackend) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+func (m *memoryBackend) Flush(_ context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	clear(m.entries)
+	clear(m.validators)
+	return nil
+}
+
+// redisBackend is a cacheBackend shared by every process pointing at the
+// same Redis. Keys are namespaced with prefix; Redis enforces the TTL.
+type redisBackend struct {
+	rdb    redis.UniversalClient
+	prefix string
+}
+
+func (r *redisBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	b, err := r.rdb.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return b, true, nil
+}
+
+func (r *redisBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.rdb.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+func (r *redisBackend) Delete(ctx context.Context, key string) error {
+	return r.rdb.Del(ctx, r.prefix+key).Err()
+}
+
+// Flush deletes only the keys under prefix, never the whole database.
+func (r *redisBackend) Flush(ctx context.Context) error {
+	iter := r.rdb.Scan(ctx, 0, r.prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		if err := r.rdb.Del(ctx, iter.Val()).Err(); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
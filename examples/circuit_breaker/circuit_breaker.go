@@ -2,16 +2,29 @@
 // - SimpleCircuitBreaker (built-in, allow/record pattern)
 // - sony/gobreaker adapter (execute pattern) via WithExecutingCircuitBreaker
 // - State transitions: Closed → Open → HalfOpen → Closed
+// - Half-open probe limit (MaxHalfOpenRequests)
+// - Fallback response while the circuit is open
+// - OnOpen / OnClose / OnHalfOpen state-change callbacks
+// - State / AllStates introspection for health endpoints
+// - Reset / ResetAll to force-close a tripped circuit
+// - Slow-call threshold: slow 200s count as failures
+// - Error-percentage tripping over a sliding window
+// - afex/hystrix-go adapter (execute pattern) via WithExecutingCircuitBreaker
 package circuitbreaker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/afex/hystrix-go/hystrix"
 	"github.com/n0l3r/httpx"
 	gbadapter "github.com/n0l3r/httpx/breaker/gobreaker"
 	gb "github.com/sony/gobreaker/v2"
@@ -27,6 +40,14 @@ func Run() {
 	exampleSimpleCBRecovery()
 	exampleGoBreakerAdapter()
 	exampleCBWithLogging()
+	exampleHalfOpenProbes()
+	exampleFallback()
+	exampleStateCallbacks()
+	exampleStateIntrospection()
+	exampleReset()
+	exampleSlowCalls()
+	exampleErrorPercentage()
+	exampleHystrixAdapter()
 }
 
 // [1] SimpleCircuitBreaker — opens after threshold failures.
@@ -191,6 +212,821 @@ func exampleCBWithLogging() {
 	}
 }
 
+// [5] Half-open probe limit — only MaxHalfOpenRequests calls test recovery.
+func exampleHalfOpenProbes() {
+	fmt.Println("\n[5] Half-open probe limit — MaxHalfOpenRequests=2, 10 concurrent calls")
+
+	var (
+		calls   atomic.Int32
+		healthy atomic.Bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		time.Sleep(50 * time.Millisecond) // keep probes in flight
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cb := newBreaker(breakerConfig{
+		CircuitBreakerConfig: httpx.CircuitBreakerConfig{
+			FailureThreshold: 2,
+			SuccessThreshold: 2,
+			OpenTimeout:      80 * time.Millisecond,
+		},
+		MaxHalfOpenRequests: 2,
+	})
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(breakerMiddleware(cb)))
+	ctx := context.Background()
+
+	for range 2 {
+		c.Get(ctx, "/api")
+	}
+	healthy.Store(true)
+	time.Sleep(90 * time.Millisecond) // open → half-open
+	calls.Store(0)
+
+	var (
+		wg       sync.WaitGroup
+		rejected atomic.Int32
+	)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get(ctx, "/api"); errors.Is(err, errCircuitOpen) {
+				rejected.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("  ✓ probes reaching server=%d, failed fast=%d\n", calls.Load(), rejected.Load())
+	_, err := c.Get(ctx, "/api")
+	fmt.Printf("  → After 2 successful probes (closed): %v\n", formatErr(err))
+}
+
+// [6] Fallback — degraded response instead of an error while open.
+func exampleFallback() {
+	fmt.Println("\n[6] Fallback response while the circuit is open")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	cfg := breakerConfig{CircuitBreakerConfig: httpx.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		SuccessThreshold: 1,
+		OpenTimeout:      time.Minute,
+	}}
+	fallback := func(req *http.Request) (*http.Response, error) {
+		fmt.Printf("    fallback for %s %s\n", req.Method, req.URL.Path)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"application/json"}, "X-Fallback": {"true"}},
+			Body:       io.NopCloser(strings.NewReader(`{"items":[],"degraded":true}`)),
+			Request:    req,
+		}, nil
+	}
+
+	withFB, _ := httpx.New(httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(breakerMiddleware(newBreaker(cfg), withFallback(fallback))))
+	withoutFB, _ := httpx.New(httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(breakerMiddleware(newBreaker(cfg))))
+	ctx := context.Background()
+
+	for range 2 {
+		withFB.Get(ctx, "/products")
+		withoutFB.Get(ctx, "/products")
+	}
+	before := calls.Load()
+
+	resp, err := withFB.Get(ctx, "/products")
+	if err == nil {
+		fmt.Printf("  ✓ with fallback: status=%d X-Fallback=%s body=%s\n",
+			resp.StatusCode(), resp.Header("X-Fallback"), resp.String())
+	}
+	_, err = withoutFB.Get(ctx, "/products")
+	fmt.Printf("  ✓ without fallback: %v\n", err)
+	fmt.Printf("    server calls while open: %d\n", calls.Load()-before)
+}
+
+// [7] State-change callbacks — alert on open, log half-open and close.
+func exampleStateCallbacks() {
+	fmt.Println("\n[7] OnOpen / OnHalfOpen / OnClose callbacks")
+
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var (
+		mu     sync.Mutex
+		events = map[string]int{}
+	)
+	on := func(event string) func(string) {
+		return func(key string) {
+			mu.Lock()
+			events[event]++
+			mu.Unlock()
+			fmt.Printf("    🔄 %s → %s\n", key, event)
+		}
+	}
+
+	cb := newBreaker(breakerConfig{
+		CircuitBreakerConfig: httpx.CircuitBreakerConfig{
+			FailureThreshold: 2,
+			SuccessThreshold: 1,
+			OpenTimeout:      50 * time.Millisecond,
+		},
+		OnOpen:     on("open"),
+		OnHalfOpen: on("half-open"),
+		OnClose:    on("closed"),
+	})
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(breakerMiddleware(cb)))
+	ctx := context.Background()
+
+	for range 3 { // third call is rejected, no extra OnOpen
+		c.Get(ctx, "/api")
+	}
+	healthy.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	c.Get(ctx, "/api") // half-open probe succeeds → closed
+	c.Get(ctx, "/api")
+
+	fmt.Printf("  ✓ open=%d half-open=%d closed=%d\n", events["open"], events["half-open"], events["closed"])
+}
+
+// [8] State introspection — expose circuit states on a health endpoint.
+func exampleStateIntrospection() {
+	fmt.Println("\n[8] State(key) / AllStates() introspection")
+
+	var healthy atomic.Bool
+	healthy.Store(true)
+	flaky := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer flaky.Close()
+	stable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer stable.Close()
+
+	cb := newBreaker(breakerConfig{CircuitBreakerConfig: httpx.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		SuccessThreshold: 1,
+		OpenTimeout:      50 * time.Millisecond,
+	}})
+	c, _ := httpx.New(httpx.WithMiddleware(breakerMiddleware(cb)))
+	ctx := context.Background()
+	key := strings.TrimPrefix(flaky.URL, "http://")
+
+	c.Get(ctx, stable.URL)
+	c.Get(ctx, flaky.URL)
+	fmt.Printf("  healthy:       %s\n", cb.State(key))
+
+	healthy.Store(false)
+	for range 2 {
+		c.Get(ctx, flaky.URL)
+	}
+	fmt.Printf("  2 failures:    %s\n", cb.State(key))
+
+	time.Sleep(60 * time.Millisecond)
+	fmt.Printf("  after timeout: %s\n", cb.State(key))
+
+	healthy.Store(true)
+	c.Get(ctx, flaky.URL)
+	fmt.Printf("  probe ok:      %s\n", cb.State(key))
+
+	fmt.Printf("  ✓ AllStates: %v\n", cb.AllStates())
+}
+
+// [9] Manual reset — force-close a circuit during incident recovery.
+func exampleReset() {
+	fmt.Println("\n[9] Reset(key) — force-close without waiting for OpenTimeout")
+
+	var (
+		calls   atomic.Int32
+		healthy atomic.Bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cb := newBreaker(breakerConfig{CircuitBreakerConfig: httpx.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		SuccessThreshold: 1,
+		OpenTimeout:      time.Hour,
+	}})
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(breakerMiddleware(cb)))
+	ctx := context.Background()
+	key := strings.TrimPrefix(srv.URL, "http://")
+
+	for range 2 {
+		c.Get(ctx, "/orders")
+	}
+	_, err := c.Get(ctx, "/orders")
+	fmt.Printf("  → Circuit %s: %v\n", cb.State(key), err)
+
+	healthy.Store(true) // the fix is deployed
+	cb.Reset(key)
+	before := calls.Load()
+	resp, err := c.Get(ctx, "/orders")
+	if err == nil {
+		fmt.Printf("  ✓ After Reset: state=%s status=%d reached server=%v\n",
+			cb.State(key), resp.StatusCode(), calls.Load() > before)
+	}
+
+	cb.ResetAll()
+	fmt.Printf("  ✓ After ResetAll: %v\n", cb.AllStates())
+}
+
+// [10] Slow-call threshold — a slow 200 is still an unhealthy server.
+func exampleSlowCalls() {
+	fmt.Println("\n[10] SlowCallThreshold=50ms, server answers 200 after 100ms")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(100 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cb := newBreaker(breakerConfig{
+		CircuitBreakerConfig: httpx.CircuitBreakerConfig{
+			FailureThreshold: 3,
+			SuccessThreshold: 1,
+			OpenTimeout:      time.Minute,
+		},
+		SlowCallThreshold: 50 * time.Millisecond,
+	})
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(breakerMiddleware(cb)))
+	ctx := context.Background()
+
+	for i := range 3 {
+		resp, err := c.Get(ctx, "/report")
+		if err == nil {
+			fmt.Printf("  call #%d → status=%d (slow)\n", i+1, resp.StatusCode())
+		}
+	}
+	_, err := c.Get(ctx, "/report")
+	fmt.Printf("  ✓ Circuit tripped by slow calls: %v (server calls=%d)\n", err, calls.Load())
+}
+
+// [11] Error percentage — trip on failure rate, not consecutive failures.
+func exampleErrorPercentage() {
+	fmt.Println("\n[11] ErrorPercentageThreshold=50%, MinimumRequests=10, window of 10")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("fail") {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cb := newBreaker(breakerConfig{
+		CircuitBreakerConfig: httpx.CircuitBreakerConfig{
+			SuccessThreshold: 1,
+			OpenTimeout:      time.Minute,
+		},
+		ErrorPercentageThreshold: 50,
+		MinimumRequests:          10,
+		WindowSize:               10,
+	})
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(breakerMiddleware(cb)))
+	ctx := context.Background()
+	key := strings.TrimPrefix(srv.URL, "http://")
+
+	// send fails the first `failures` of 10 calls.
+	send := func(failures int) {
+		for i := range 10 {
+			path := "/search"
+			if i < failures {
+				path += "?fail=1"
+			}
+			c.Get(ctx, path)
+		}
+	}
+
+	send(4)
+	fmt.Printf("  40%% failures (4 in a row): %s\n", cb.State(key))
+	send(6)
+	fmt.Printf("  60%% failures:              %s\n", cb.State(key))
+	_, err := c.Get(ctx, "/search")
+	fmt.Printf("  ✓ next call: %v\n", err)
+}
+
+// [12] hystrix-go adapter — hystrix.CommandConfig behind WithExecutingCircuitBreaker.
+func exampleHystrixAdapter() {
+	fmt.Println("\n[12] hystrix-go adapter (execute pattern)")
+
+	hystrix.Flush() // drop circuits and metrics left by earlier runs
+
+	var failing atomic.Bool
+	failing.Store(true)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	adapter := newHystrixBreaker("demo-hystrix", hystrix.CommandConfig{
+		Timeout:                50, // ms
+		MaxConcurrentRequests:  10,
+		RequestVolumeThreshold: 4,
+		SleepWindow:            100, // ms
+		ErrorPercentThreshold:  50,
+	})
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithTimeout(100*time.Millisecond), // cancels requests hystrix stopped waiting for
+		httpx.WithExecutingCircuitBreaker(adapter),
+	)
+	ctx := context.Background()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	for range 4 {
+		c.Get(ctx, "/api")
+	}
+	time.Sleep(20 * time.Millisecond) // hystrix collects metrics asynchronously
+	fmt.Printf("  after 4 × 500: %s\n", adapter.State(host))
+
+	_, err := c.Get(ctx, "/api")
+	fmt.Printf("  ✓ rejected: errors.Is(httpx.ErrCircuitOpen)=%v, errors.Is(hystrix.ErrCircuitOpen)=%v\n",
+		errors.Is(err, httpx.ErrCircuitOpen), errors.Is(err, hystrix.ErrCircuitOpen))
+
+	failing.Store(false)
+	time.Sleep(110 * time.Millisecond) // SleepWindow passes: one trial call goes through
+	resp, err := c.Get(ctx, "/api")
+	if err != nil {
+		fmt.Printf("  ✗ trial call: %v\n", err)
+		return
+	}
+	fmt.Printf("  ✓ trial call %d → %s\n", resp.StatusCode(), adapter.State(host))
+
+	_, err = c.Get(ctx, "/slow")
+	fmt.Printf("  ✓ 100ms call, 50ms Timeout: errors.Is(hystrix.ErrTimeout)=%v, errors.Is(httpx.ErrCircuitOpen)=%v\n",
+		errors.Is(err, hystrix.ErrTimeout), errors.Is(err, httpx.ErrCircuitOpen))
+}
+
+// ---
+
+// errCircuitOpen is returned by breakerMiddleware when a call is rejected.
+var errCircuitOpen = errors.New("circuit breaker: circuit open")
+
+// breakerConfig extends httpx.CircuitBreakerConfig with the knobs the
+// local breaker adds on top of SimpleCircuitBreaker.
+type breakerConfig struct {
+	httpx.CircuitBreakerConfig
+
+	// MaxHalfOpenRequests caps concurrent calls while half-open.
+	// Zero means unlimited.
+	MaxHalfOpenRequests int
+
+	// SlowCallThreshold counts calls slower than this as failures, whatever
+	// their status. Zero disables it.
+	SlowCallThreshold time.Duration
+
+	// ErrorPercentageThreshold opens the circuit when more than this
+	// percentage (0–100) of the last WindowSize calls failed, once at least
+	// MinimumRequests calls are in the window. Zero disables it; set
+	// FailureThreshold to zero to use it alone.
+	ErrorPercentageThreshold float64
+	MinimumRequests          int
+	WindowSize               int // zero means 20
+
+	// OnOpen, OnClose and OnHalfOpen are called with the key whose circuit
+	// changed state. They run outside the breaker lock.
+	OnOpen     func(key string)
+	OnClose    func(key string)
+	OnHalfOpen func(key string)
+}
+
+type circuitState int
+
+const (
+	stateClosed circuitState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case stateOpen:
+		return "open"
+	case stateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// circuit is the per-key state tracked by breaker.
+type circuit struct {
+	state     circuitState
+	failures  int
+	successes int
+	openedAt  time.Time
+	probes    int            // half-open calls in flight
+	window    *outcomeWindow // nil unless ErrorPercentageThreshold is set
+}
+
+// breaker is a per-key circuit breaker with the same allow/record shape as
+// httpx.SimpleCircuitBreaker. Install it with breakerMiddleware.
+type breaker struct {
+	cfg breakerConfig
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+func newBreaker(cfg breakerConfig) *breaker {
+	return &breaker{cfg: cfg, circuits: make(map[string]*circuit)}
+}
+
+// Allow reports whether a call for key may proceed. A nil result must be
+// followed by exactly one Record call.
+func (b *breaker) Allow(key string) error {
+	b.mu.Lock()
+	c := b.circuit(key)
+	from := c.state
+	err := b.allow(c)
+	to := c.state
+	b.mu.Unlock()
+
+	b.notify(key, from, to)
+	return err
+}
+
+// Record reports the outcome of a call admitted by Allow.
+func (b *breaker) Record(key string, success bool) {
+	b.mu.Lock()
+	c := b.circuit(key)
+	from := c.state
+	b.record(c, success)
+	to := c.state
+	b.mu.Unlock()
+
+	b.notify(key, from, to)
+}
+
+// State returns "closed", "open" or "half-open" for key. An open circuit
+// whose OpenTimeout has elapsed reports "half-open": the next call is a probe.
+func (b *breaker) State(key string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	c, ok := b.circuits[key]
+	if !ok {
+		return stateClosed.String()
+	}
+	return b.effectiveState(c).String()
+}
+
+// AllStates returns the state of every key the breaker has seen.
+func (b *breaker) AllStates() map[string]string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := make(map[string]string, len(b.circuits))
+	for key, c := range b.circuits {
+		out[key] = b.effectiveState(c).String()
+	}
+	return out
+}
+
+// Reset force-closes the circuit for key and zeroes its counters, e.g. once
+// an incident is resolved and waiting for OpenTimeout is not wanted.
+func (b *breaker) Reset(key string) {
+	b.mu.Lock()
+	c, ok := b.circuits[key]
+	if !ok {
+		b.mu.Unlock()
+		return
+	}
+	from := c.state
+	b.close(c)
+	b.mu.Unlock()
+
+	b.notify(key, from, stateClosed)
+}
+
+// ResetAll force-closes every tracked circuit.
+func (b *breaker) ResetAll() {
+	b.mu.Lock()
+	previous := make(map[string]circuitState)
+	for key, c := range b.circuits {
+		previous[key] = c.state
+		b.close(c)
+	}
+	b.mu.Unlock()
+
+	for key, from := range previous {
+		b.notify(key, from, stateClosed)
+	}
+}
+
+func (b *breaker) effectiveState(c *circuit) circuitState {
+	if c.state == stateOpen && time.Since(c.openedAt) >= b.cfg.OpenTimeout {
+		return stateHalfOpen
+	}
+	return c.state
+}
+
+func (b *breaker) allow(c *circuit) error {
+	if c.state == stateOpen {
+		if time.Since(c.openedAt) < b.cfg.OpenTimeout {
+			return errCircuitOpen
+		}
+		c.state, c.successes, c.probes = stateHalfOpen, 0, 0
+	}
+	if c.state == stateHalfOpen {
+		if b.cfg.MaxHalfOpenRequests > 0 && c.probes >= b.cfg.MaxHalfOpenRequests {
+			return errCircuitOpen
+		}
+		c.probes++
+	}
+	return nil
+}
+
+func (b *breaker) record(c *circuit, success bool) {
+	switch c.state {
+	case stateClosed:
+		if c.window != nil {
+			c.window.add(!success)
+			if c.window.count >= b.cfg.MinimumRequests &&
+				c.window.failurePercent() > b.cfg.ErrorPercentageThreshold {
+				b.trip(c)
+				return
+			}
+		}
+		if success {
+			c.failures = 0
+			return
+		}
+		if c.failures++; b.cfg.FailureThreshold > 0 && c.failures >= b.cfg.FailureThreshold {
+			b.trip(c)
+		}
+	case stateHalfOpen:
+		if c.probes > 0 {
+			c.probes--
+		}
+		if !success {
+			b.trip(c)
+			return
+		}
+		if c.successes++; c.successes >= b.cfg.SuccessThreshold {
+			b.close(c)
+		}
+	}
+	// Open: a late result from a call admitted before the trip; ignore it.
+}
+
+// notify fires the state-change callback for key, if any.
+func (b *breaker) notify(key string, from, to circuitState) {
+	if from == to {
+		return
+	}
+	var fn func(string)
+	switch to {
+	case stateOpen:
+		fn = b.cfg.OnOpen
+	case stateClosed:
+		fn = b.cfg.OnClose
+	case stateHalfOpen:
+		fn = b.cfg.OnHalfOpen
+	}
+	if fn != nil {
+		fn(key)
+	}
+}
+
+func (b *breaker) circuit(key string) *circuit {
+	c, ok := b.circuits[key]
+	if !ok {
+		c = &circuit{}
+		if b.cfg.ErrorPercentageThreshold > 0 {
+			size := b.cfg.WindowSize
+			if size <= 0 {
+				size = 20
+			}
+			c.window = &outcomeWindow{failed: make([]bool, size)}
+		}
+		b.circuits[key] = c
+	}
+	return c
+}
+
+func (b *breaker) trip(c *circuit) {
+	c.state, c.openedAt, c.probes = stateOpen, time.Now(), 0
+	if c.window != nil {
+		c.window.reset()
+	}
+}
+
+// close returns c to closed with every counter zeroed.
+func (b *breaker) close(c *circuit) {
+	*c = circuit{window: c.window}
+	if c.window != nil {
+		c.window.reset()
+	}
+}
+
+// outcomeWindow is a fixed-size ring buffer of the most recent call
+// outcomes, used for percentage-based tripping.
+type outcomeWindow struct {
+	failed   []bool
+	next     int
+	count    int
+	failures int
+}
+
+func (w *outcomeWindow) add(failed bool) {
+	if w.count == len(w.failed) {
+		if w.failed[w.next] {
+			w.failures-- // overwriting the oldest outcome
+		}
+	} else {
+		w.count++
+	}
+	w.failed[w.next] = failed
+	if failed {
+		w.failures++
+	}
+	w.next = (w.next + 1) % len(w.failed)
+}
+
+func (w *outcomeWindow) failurePercent() float64 {
+	if w.count == 0 {
+		return 0
+	}
+	return 100 * float64(w.failures) / float64(w.count)
+}
+
+func (w *outcomeWindow) reset() {
+	clear(w.failed)
+	w.next, w.count, w.failures = 0, 0, 0
+}
+
+// breakerOption configures breakerMiddleware.
+type breakerOption func(*breakerTransport)
+
+// withFallback answers calls rejected by an open circuit with fn instead of
+// errCircuitOpen. fn receives the original request.
+func withFallback(fn func(*http.Request) (*http.Response, error)) breakerOption {
+	return func(t *breakerTransport) { t.fallback = fn }
+}
+
+// breakerMiddleware guards every request with b, keyed by host. Transport
+// errors, 5xx responses and calls slower than SlowCallThreshold count as
+// failures.
+func breakerMiddleware(b *breaker, opts ...breakerOption) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		t := &breakerTransport{next: next, breaker: b}
+		for _, opt := range opts {
+			opt(t)
+		}
+		return t
+	}
+}
+
+// breakerTransport is the http.RoundTripper installed by breakerMiddleware.
+type breakerTransport struct {
+	next     http.RoundTripper
+	breaker  *breaker
+	fallback func(*http.Request) (*http.Response, error)
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.URL.Host
+	if err := t.breaker.Allow(key); err != nil {
+		if t.fallback != nil {
+			return t.fallback(req)
+		}
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	success := err == nil && resp.StatusCode < http.StatusInternalServerError
+	if slow := t.breaker.cfg.SlowCallThreshold; slow > 0 && time.Since(start) > slow {
+		success = false
+	}
+	t.breaker.Record(key, success)
+	return resp, err
+}
+
+// hystrixBreaker adapts hystrix-go to httpx.ExecutingCircuitBreaker, as
+// breaker/gobreaker does for sony/gobreaker: one hystrix command per host,
+// named "<name>:<host>" and configured with cfg on first use. Transport
+// errors and 5xx responses count as failures; a 5xx is still returned to
+// the caller. A rejected call returns httpx.ErrCircuitOpen wrapping
+// hystrix.ErrCircuitOpen. A call slower than cfg.Timeout returns
+// hystrix.ErrTimeout on its own: the host was slow, not the circuit open.
+//
+// hystrix cannot cancel fn, so a timed-out request keeps running until its
+// own deadline; give the client a timeout no longer than cfg.Timeout.
+type hystrixBreaker struct {
+	name string
+	cfg  hystrix.CommandConfig
+
+	mu         sync.Mutex
+	configured map[string]bool // command names already configured
+}
+
+// newHystrixBreaker returns an adapter whose per-host commands use cfg; zero
+// fields take hystrix's defaults.
+func newHystrixBreaker(name string, cfg hystrix.CommandConfig) *hystrixBreaker {
+	return &hystrixBreaker{name: name, cfg: cfg, configured: map[string]bool{}}
+}
+
+// command returns the hystrix command name for host, configuring it once.
+func (b *hystrixBreaker) command(host string) string {
+	name := b.name + ":" + host
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.configured[name] {
+		hystrix.ConfigureCommand(name, b.cfg)
+		b.configured[name] = true
+	}
+	return name
+}
+
+// State returns "open" or "closed" for host's command.
+func (b *hystrixBreaker) State(host string) string {
+	if cb, _, err := hystrix.GetCircuit(b.command(host)); err == nil && cb.IsOpen() {
+		return "open"
+	}
+	return "closed"
+}
+
+// errHystrixServerError reports a 5xx response to hystrix as a failure.
+var errHystrixServerError = errors.New("hystrix: server error response")
+
+// Execute implements httpx.ExecutingCircuitBreaker.
+func (b *hystrixBreaker) Execute(host string, fn func() (*http.Response, error)) (*http.Response, error) {
+	type result struct {
+		resp *http.Response
+		err  error
+	}
+	command := b.command(host)
+	done := make(chan result, 1)
+	err := hystrix.Do(command, func() error {
+		resp, err := fn()
+		done <- result{resp, err}
+		if err == nil && resp.StatusCode >= http.StatusInternalServerError {
+			return errHystrixServerError
+		}
+		return err
+	}, nil)
+
+	switch {
+	case errors.Is(err, hystrix.ErrTimeout):
+		// The call is still running; close its response when it lands.
+		go func() {
+			if r := <-done; r.resp != nil {
+				r.resp.Body.Close()
+			}
+		}()
+		return nil, fmt.Errorf("%s: %w", command, err)
+	case errors.Is(err, hystrix.ErrCircuitOpen):
+		return nil, fmt.Errorf("%w: %s: %w", httpx.ErrCircuitOpen, command, err)
+	case errors.Is(err, hystrix.ErrMaxConcurrency):
+		return nil, fmt.Errorf("%s: %w", command, err)
+	}
+	r := <-done
+	return r.resp, r.err
+}
+
 func formatErr(err error) string {
 	if err == nil {
 		return "nil (allowed)"
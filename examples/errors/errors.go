@@ -0,0 +1,328 @@
+// Package errorsdemo demonstrates structured error handling with httpx:
+// - Typed HTTP errors with status, body and headers
+// - Classifying network and retryable errors
+// - Telling a canceled request from one that ran out of time
+package errorsdemo
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/n0l3r/httpx"
+)
+
+// Sentinels matched with errors.Is against an *httpError.
+var (
+	errUnauthorized    = errors.New("unauthorized")
+	errForbidden       = errors.New("forbidden")
+	errNotFound        = errors.New("not found")
+	errTooManyRequests = errors.New("too many requests")
+	errServerError     = errors.New("server error")
+)
+
+// Run executes all error handling examples.
+func Run() {
+	fmt.Println("\n═══════════════════════════════════════════")
+	fmt.Println("  ERROR HANDLING EXAMPLES")
+	fmt.Println("═══════════════════════════════════════════")
+
+	exampleHTTPError()
+	exampleClassifiers()
+	exampleContextErrors()
+}
+
+// [1] Typed error — errors.As for details, errors.Is for the status class.
+func exampleHTTPError() {
+	fmt.Println("\n[1] httpError — errors.As / errors.Is instead of string matching")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders/404":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"code":"order_not_found","message":"no order 404"}`)
+		case "/orders/busy":
+			w.Header().Set("Retry-After", "30")
+			w.Header().Add("X-RateLimit-Policy", "100;w=60")
+			w.Header().Add("X-RateLimit-Policy", "1000;w=3600")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL))
+
+	for _, path := range []string{"/orders/1", "/orders/404", "/orders/busy"} {
+		resp, err := c.Get(context.Background(), path)
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			continue
+		}
+		err = ensureSuccess(resp)
+		if err == nil {
+			fmt.Printf("  ✓ %-13s ok\n", path)
+			continue
+		}
+
+		var httpErr *httpError
+		if !errors.As(err, &httpErr) {
+			fmt.Printf("  ✗ unexpected error type %T\n", err)
+			continue
+		}
+		var libErr *httpx.Error
+		fmt.Printf("  ✓ %-13s %v (wraps *httpx.Error: %v, IsStatus4xx: %v)\n",
+			path, err, errors.As(err, &libErr), httpx.IsStatus4xx(err))
+		switch {
+		case errors.Is(err, errNotFound):
+			var apiErr struct {
+				Code string `json:"code"`
+			}
+			httpErr.JSON(&apiErr)
+			fmt.Printf("    not found, API code %q (%d body bytes)\n", apiErr.Code, len(httpErr.Body))
+		case errors.Is(err, errTooManyRequests):
+			fmt.Printf("    rate limited, Retry-After: %s, policies: %q\n",
+				httpErr.Header.Get("Retry-After"), httpErr.Header.Values("X-RateLimit-Policy"))
+
+			// Round-trip through JSON, e.g. a job queue's failure record.
+			wire, _ := json.Marshal(httpErr)
+			var decoded *httpError
+			if err := json.Unmarshal(wire, &decoded); err != nil {
+				fmt.Printf("  ✗ %v\n", err)
+				continue
+			}
+			fmt.Printf("    decoded from JSON: %v, errors.Is(errTooManyRequests)=%v, IsStatus4xx=%v\n",
+				decoded, errors.Is(decoded, errTooManyRequests), httpx.IsStatus4xx(decoded))
+		}
+	}
+}
+
+// [2] Classifiers — decide whether to retry outside the middleware chain.
+func exampleClassifiers() {
+	fmt.Println("\n[2] isNetworkError / isRetryable")
+
+	c, _ := httpx.New()
+	_, refused := c.Get(context.Background(), "http://127.0.0.1:1/") // nothing listens on port 1
+
+	tlsSrv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsSrv.Close()
+	_, untrusted := c.Get(context.Background(), tlsSrv.URL) // self-signed: not in the system roots
+
+	expiredCtx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	_, expired := c.Get(expiredCtx, tlsSrv.URL)
+
+	cases := []struct {
+		name string
+		err  error
+	}{
+		{"connection refused", refused},
+		{"connection reset", &url.Error{Op: "Get", URL: "http://api", Err: &net.OpError{
+			Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}},
+		{"unexpected EOF", fmt.Errorf("read body: %w", io.ErrUnexpectedEOF)},
+		{"deadline exceeded", fmt.Errorf("get: %w", context.DeadlineExceeded)},
+		{"expired deadline", expired},
+		{"untrusted cert", untrusted},
+		{"url.Error only", &url.Error{Op: "Get", URL: "http://api", Err: errors.New("unsupported protocol scheme")}},
+		{"503 response", &httpError{StatusCode: http.StatusServiceUnavailable}},
+		{"429 response", &httpError{StatusCode: http.StatusTooManyRequests}},
+		{"404 response", &httpError{StatusCode: http.StatusNotFound}},
+		{"httpx 502", &httpx.Error{StatusCode: http.StatusBadGateway, Kind: httpx.ErrorKindStatus5xx}},
+		{"httpx 429", &httpx.Error{StatusCode: http.StatusTooManyRequests, Kind: httpx.ErrorKindStatus4xx}},
+		{"httpx network", &httpx.Error{Op: "GET", URL: "http://api", Kind: httpx.ErrorKindNetwork,
+			Err: errors.New("connection lost")}},
+		{"caller canceled", &url.Error{Op: "Get", URL: "http://api", Err: context.Canceled}},
+		{"validation error", errors.New("amount must be positive")},
+	}
+	for _, tc := range cases {
+		fmt.Printf("  ✓ %-18s network=%-5v retryable=%v\n", tc.name, isNetworkError(tc.err), isRetryable(tc.err))
+	}
+}
+
+// [3] Context errors — the caller gave up vs. the deadline passed.
+func exampleContextErrors() {
+	fmt.Println("\n[3] isContextCanceled / isDeadlineExceeded")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL))
+
+	cancelCtx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, canceled := c.Get(cancelCtx, "/slow")
+
+	timeoutCtx, cancelTimeout := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelTimeout()
+	_, expired := c.Get(timeoutCtx, "/slow")
+
+	for _, tc := range []struct {
+		name string
+		err  error
+	}{
+		{"WithCancel", canceled},
+		{"WithTimeout", expired},
+	} {
+		fmt.Printf("  ✓ %-11s canceled=%-5v deadline=%-5v timeout=%v\n", tc.name,
+			isContextCanceled(tc.err), isDeadlineExceeded(tc.err), httpx.IsTimeout(tc.err))
+	}
+	fmt.Println("  ✓ only the deadline is worth reporting upstream; a cancel was the caller's choice")
+}
+
+// ---
+
+// httpError is a non-2xx response as an error. It wraps the *httpx.Error
+// from resp.EnsureSuccess, so errors.As(err, new(*httpx.Error)) and the
+// library classifiers still work, and adds a sentinel for the status
+// (errNotFound, errServerError, ...) for errors.Is plus the body and headers.
+type httpError struct {
+	StatusCode int         `json:"status_code"`
+	Body       []byte      `json:"body"`
+	Header     http.Header `json:"header"`
+
+	err *httpx.Error
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("http %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Unwrap returns the sentinel for the status code, if any, and the wrapped
+// *httpx.Error.
+func (e *httpError) Unwrap() []error {
+	var errs []error
+	switch {
+	case e.StatusCode == http.StatusUnauthorized:
+		errs = append(errs, errUnauthorized)
+	case e.StatusCode == http.StatusForbidden:
+		errs = append(errs, errForbidden)
+	case e.StatusCode == http.StatusNotFound:
+		errs = append(errs, errNotFound)
+	case e.StatusCode == http.StatusTooManyRequests:
+		errs = append(errs, errTooManyRequests)
+	case e.StatusCode >= 500:
+		errs = append(errs, errServerError)
+	}
+	if e.err != nil {
+		errs = append(errs, e.err)
+	}
+	return errs
+}
+
+// UnmarshalJSON implements json.Unmarshaler for errors passed between
+// processes, e.g. through a job queue: it restores the fields and rebuilds
+// the wrapped *httpx.Error, so the decoded error matches errors.As and the
+// library classifiers like the original.
+func (e *httpError) UnmarshalJSON(data []byte) error {
+	type wire httpError // no methods, so no recursion
+	var w wire
+	if err := json.Unmarshal(data, &w); err != nil {
+		return err
+	}
+	*e = httpError(w)
+	kind := httpx.ErrorKindStatus4xx
+	if e.StatusCode >= 500 {
+		kind = httpx.ErrorKindStatus5xx
+	}
+	e.err = &httpx.Error{StatusCode: e.StatusCode, Kind: kind, Err: fmt.Errorf("unexpected status: %d", e.StatusCode)}
+	return nil
+}
+
+// JSON decodes the error body, e.g. into an API error envelope.
+func (e *httpError) JSON(v any) error {
+	return json.Unmarshal(e.Body, v)
+}
+
+// ensureSuccess is resp.EnsureSuccess returning an *httpError, carrying the
+// body and a copy of the full header map, for non-2xx responses.
+func ensureSuccess(resp *httpx.Response) error {
+	err := resp.EnsureSuccess()
+	if err == nil {
+		return nil
+	}
+	e := &httpError{StatusCode: resp.StatusCode(), Body: resp.Bytes()}
+	if resp.Raw != nil {
+		e.Header = resp.Raw.Header.Clone()
+	}
+	errors.As(err, &e.err)
+	return e
+}
+
+// isNetworkError reports whether err came from the network rather than the
+// server's answer: dial/read/write failures (*net.OpError), DNS lookups,
+// refused or reset connections, and truncated bodies. The *url.Error the
+// client wraps everything in does not count by itself, and neither do
+// context cancellation, an expired deadline or certificate errors.
+func isNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var certErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &certErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return false
+	}
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	return errors.As(err, &opErr) ||
+		errors.As(err, &dnsErr) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isRetryable reports whether repeating the request may succeed: network
+// errors, timeouts, and 5xx or 429 responses, whether they come as an
+// *httpError, an *httpx.Error from the library or a raw transport error. A
+// canceled or expired context is never retryable; the caller has stopped
+// waiting.
+func isRetryable(err error) bool {
+	if isContextCanceled(err) || isDeadlineExceeded(err) {
+		return false
+	}
+	if isNetworkError(err) || httpx.IsNetworkError(err) || httpx.IsTimeout(err) || httpx.IsStatus5xx(err) {
+		return true
+	}
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	var libErr *httpx.Error
+	if errors.As(err, &libErr) {
+		return libErr.StatusCode == http.StatusTooManyRequests
+	}
+	return false
+}
+
+// isContextCanceled reports whether err's chain, including *url.Error
+// wrappers, contains context.Canceled.
+func isContextCanceled(err error) bool {
+	return errors.Is(err, context.Canceled)
+}
+
+// isDeadlineExceeded reports whether err's chain, including *url.Error
+// wrappers, contains context.DeadlineExceeded.
+func isDeadlineExceeded(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,217 @@
+// Package logging demonstrates structured logging for httpx clients:
+// - log/slog via httpx.WithSlogLogger, plus a LogHookFunc for 5xx at Warn
+// - go.uber.org/zap via a LogHookFunc with a level check
+// - Any slog.Handler directly, without a Logger
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/n0l3r/httpx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Run executes all logging examples.
+func Run() {
+	fmt.Println("\n═══════════════════════════════════════════")
+	fmt.Println("  STRUCTURED LOGGING EXAMPLES")
+	fmt.Println("═══════════════════════════════════════════")
+
+	exampleSlogLogger()
+	exampleZapLogger()
+	exampleSlogHandler()
+}
+
+// [1] slog — one structured record per request.
+func exampleSlogLogger() {
+	fmt.Println("\n[1] slog logger — WithSlogLogger, and a hook for 5xx at Warn")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/boom" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: dropTime, // stable output for the demo
+	}))
+
+	// The built-in slog support: Error on failure, Info otherwise.
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithSlogLogger(logger))
+	c.Get(context.Background(), "/users")
+	c.Get(context.Background(), "/boom")
+
+	// The same record, with a 5xx response raised to Warn.
+	warn, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithLogHook(slogLogHook(logger)))
+	warn.Get(context.Background(), "/boom")
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		fmt.Printf("  ✓ %s\n", line)
+	}
+}
+
+// [2] zap — typed fields, and nothing allocated below the logger's level.
+func exampleZapLogger() {
+	fmt.Println("\n[2] zap logger — http.* fields via logger.Check")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.TimeKey = "" // stable output for the demo
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(encoderCfg), zapcore.AddSync(&buf), zapcore.InfoLevel))
+
+	info, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithLogHook(zapLogHook(logger, zapcore.InfoLevel)))
+	debug, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithLogHook(zapLogHook(logger, zapcore.DebugLevel)))
+
+	info.Post(context.Background(), "/orders", httpx.WithJSONBody(map[string]int{"qty": 1}))
+	debug.Get(context.Background(), "/orders") // below InfoLevel: Check returns nil, no fields built
+
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		fmt.Printf("  ✓ %s\n", line)
+	}
+}
+
+// [3] slog.Handler — hand records straight to any handler.
+func exampleSlogHandler() {
+	fmt.Println("\n[3] slog.Handler hook — JSON records parsed back")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: dropTime})
+
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithLogHook(slogHandlerHook(h)))
+	c.Get(context.Background(), "/users/404")
+
+	var record struct {
+		Level      string  `json:"level"`
+		Method     string  `json:"http.method"`
+		URL        string  `json:"http.url"`
+		StatusCode int     `json:"http.status_code"`
+		DurationMS float64 `json:"http.duration_ms"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	fmt.Printf("  ✓ %s\n", strings.TrimSpace(buf.String()))
+	fmt.Printf("  ✓ parsed: level=%s %s %s → %d in %.1fms\n",
+		record.Level, record.Method, record.URL, record.StatusCode, record.DurationMS)
+}
+
+// ---
+
+// slogLogHook logs the same record as httpx.WithSlogLogger but at Warn for
+// 5xx responses, which WithSlogLogger logs at Info; it exists only for that
+// level. Use WithSlogLogger when Info is fine.
+func slogLogHook(logger *slog.Logger) httpx.LogHookFunc {
+	return func(e httpx.LogEvent) {
+		level := slog.LevelInfo
+		attrs := []slog.Attr{
+			slog.String("method", e.Method),
+			slog.String("url", e.URL),
+			slog.Int("status", e.StatusCode),
+			slog.Duration("duration", e.Duration),
+		}
+		if e.Attempt > 0 {
+			attrs = append(attrs, slog.Int("attempt", e.Attempt))
+		}
+		switch {
+		case e.Err != nil:
+			level = slog.LevelError
+			attrs = append(attrs, slog.String("error", e.Err.Error()))
+		case e.StatusCode >= 500:
+			level = slog.LevelWarn
+		}
+		logger.LogAttrs(context.Background(), level, "http request", attrs...)
+	}
+}
+
+// zapLogHook logs each request at level (Error if the request failed) with
+// http.method, http.url, http.status_code and http.duration fields. The
+// level is checked first, so disabled hooks build no fields.
+func zapLogHook(logger *zap.Logger, level zapcore.Level) httpx.LogHookFunc {
+	return func(e httpx.LogEvent) {
+		lvl := level
+		if e.Err != nil {
+			lvl = zapcore.ErrorLevel
+		}
+		ce := logger.Check(lvl, "http request")
+		if ce == nil {
+			return
+		}
+		fields := []zap.Field{
+			zap.String("http.method", e.Method),
+			zap.String("http.url", e.URL),
+			zap.Int("http.status_code", e.StatusCode),
+			zap.Duration("http.duration", e.Duration),
+		}
+		if e.Err != nil {
+			fields = append(fields, zap.Error(e.Err))
+		}
+		ce.Write(fields...)
+	}
+}
+
+// slogHandlerHook sends each request to h as a record with http.method,
+// http.url, http.status_code, http.duration_ms and, for retried requests,
+// http.attempt attributes, at Error when
+// the request failed, Warn for 5xx and Info otherwise. Records below the
+// handler's level are never built.
+func slogHandlerHook(h slog.Handler) httpx.LogHookFunc {
+	return func(e httpx.LogEvent) {
+		ctx := context.Background()
+		level := slog.LevelInfo
+		switch {
+		case e.Err != nil:
+			level = slog.LevelError
+		case e.StatusCode >= 500:
+			level = slog.LevelWarn
+		}
+		if !h.Enabled(ctx, level) {
+			return
+		}
+		r := slog.NewRecord(time.Now(), level, "http request", 0)
+		r.AddAttrs(
+			slog.String("http.method", e.Method),
+			slog.String("http.url", e.URL),
+			slog.Int("http.status_code", e.StatusCode),
+			slog.Float64("http.duration_ms", float64(e.Duration)/float64(time.Millisecond)),
+		)
+		if e.Attempt > 0 {
+			r.AddAttrs(slog.Int("http.attempt", e.Attempt))
+		}
+		if e.Err != nil {
+			r.AddAttrs(slog.String("error", e.Err.Error()))
+		}
+		h.Handle(ctx, r)
+	}
+}
+
+// dropTime removes the time attribute from records.
+func dropTime(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	return a
+}
@@ -6,19 +6,44 @@
 // - SingleflightMiddleware
 // - Before/After hooks
 // - Middleware chaining order
+// - Structured request/response logging with header redaction
+// - Gzip request body compression
+// - gzip / deflate response decompression
+// - Request ID propagated from the context
+// - Prometheus metrics: request count, duration, in-flight
+// - Panic recovery in the transport chain
+// - Wire dump of requests and responses for debugging
+// - Conditional middleware (apply only to matching requests)
+// - Response body size limit
+// - User-Agent middleware with per-request override
 package middleware
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/n0l3r/httpx"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Run executes all middleware examples.
@@ -34,6 +59,16 @@ func Run() {
 	exampleSingleflightMiddleware()
 	exampleMiddlewareChainOrder()
 	exampleBeforeAfterHooks()
+	exampleLoggingMiddleware()
+	exampleGzipRequest()
+	exampleDecompression()
+	exampleRequestIDFromContext()
+	examplePrometheusMetrics()
+	exampleRecoveryMiddleware()
+	exampleDumpMiddleware()
+	exampleConditionalMiddleware()
+	exampleMaxBodySize()
+	exampleUserAgent()
 }
 
 // [1] Custom middleware — log timing per request.
@@ -237,8 +272,799 @@ func exampleBeforeAfterHooks() {
 	c.Post(context.Background(), "/orders", httpx.WithJSONBody(map[string]string{"item": "book"}))
 }
 
+// [8] Logging middleware — one structured line per request, secrets redacted.
+func exampleLoggingMiddleware() {
+	fmt.Println("\n[8] Logging middleware — slog, redacted headers, truncated bodies")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":42,"name":"Widget","description":"a rather long description"}`)
+	}))
+	defer srv.Close()
+
+	// Drop the timestamp so the output is stable.
+	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithDefaultHeader("Authorization", "Bearer s3cr3t"),
+		httpx.WithMiddleware(loggingMiddleware(logger, loggingConfig{
+			RedactHeaders: []string{"Authorization", "Cookie"},
+			LogBodies:     true,
+			MaxBodyBytes:  24,
+		})),
+	)
+
+	req, _ := c.NewRequest(context.Background(), "GET", "/items/42").
+		Header("X-Request-ID", "req-7f3a").
+		Build()
+	resp, _ := c.Do(req)
+	c.Get(context.Background(), "/missing")
+
+	fmt.Printf("  ✓ caller still reads the full body: %d bytes\n", len(resp.Bytes()))
+}
+
+// [9] Gzip request compression — large bodies are sent compressed.
+func exampleGzipRequest() {
+	fmt.Println("\n[9] Gzip request middleware — compress bodies ≥ 1 KiB")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wire, _ := io.ReadAll(r.Body)
+		body := wire
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(bytes.NewReader(wire))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body, _ = io.ReadAll(zr)
+		}
+		// Answer gzip-encoded whenever the client accepts it.
+		var out io.Writer = w
+		if strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			w.Header().Set("Content-Encoding", "gzip")
+			zw := gzip.NewWriter(w)
+			defer zw.Close()
+			out = zw
+		}
+		fmt.Fprintf(out, "encoding=%q accept=%q length=%d wire=%dB body=%dB",
+			r.Header.Get("Content-Encoding"), r.Header.Get("Accept-Encoding"), r.ContentLength, len(wire), len(body))
+	}))
+	defer srv.Close()
+
+	// decompressionMiddleware decodes the gzip responses gzipRequestMiddleware asks for.
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(gzipRequestMiddleware(1024, gzip.BestSpeed), decompressionMiddleware()),
+	)
+
+	docs := make([]map[string]string, 200)
+	for i := range docs {
+		docs[i] = map[string]string{"index": "products", "status": "in_stock"}
+	}
+	small, _ := c.Post(context.Background(), "/_doc", httpx.WithJSONBody(map[string]string{"id": "1"}))
+	bulk, _ := c.Post(context.Background(), "/_bulk", httpx.WithJSONBody(docs))
+
+	fmt.Printf("  small: %s\n", small.String())
+	fmt.Printf("  ✓ bulk: %s\n", bulk.String())
+}
+
+// [10] Decompression — decode gzip/deflate bodies net/http left encoded.
+func exampleDecompression() {
+	fmt.Println("\n[10] Decompression middleware — gzip and deflate responses")
+
+	const payload = `{"items":["alpha","beta","gamma"]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var zw io.WriteCloser
+		switch r.URL.Path {
+		case "/gzip":
+			zw = gzip.NewWriter(w)
+		case "/deflate":
+			zw = zlib.NewWriter(w)
+		}
+		w.Header().Set("Content-Encoding", r.URL.Path[1:])
+		io.WriteString(zw, payload)
+		zw.Close()
+	}))
+	defer srv.Close()
+
+	// An explicit Accept-Encoding turns off net/http's own gzip handling.
+	raw, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithDefaultHeader("Accept-Encoding", "gzip, deflate"),
+	)
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithDefaultHeader("Accept-Encoding", "gzip, deflate"),
+		httpx.WithMiddleware(decompressionMiddleware()),
+	)
+
+	resp, _ := raw.Get(context.Background(), "/gzip")
+	fmt.Printf("  without middleware: %d encoded bytes\n", len(resp.Bytes()))
+	for _, path := range []string{"/gzip", "/deflate"} {
+		resp, err := c.Get(context.Background(), path)
+		if err != nil {
+			fmt.Printf("  ✗ %s: %v\n", path, err)
+			continue
+		}
+		fmt.Printf("  ✓ %-8s → %s (Content-Encoding=%q)\n", path, resp.String(), resp.Header("Content-Encoding"))
+	}
+}
+
+// [11] Request ID from context — reuse the inbound ID on outbound calls.
+func exampleRequestIDFromContext() {
+	fmt.Println("\n[11] Request ID from context — propagate, or generate when missing")
+
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("X-Request-ID"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	// requestID is how an inbound HTTP handler would have stored the ID.
+	type requestIDKey struct{}
+	requestID := func(ctx context.Context) string {
+		id, _ := ctx.Value(requestIDKey{}).(string)
+		return id
+	}
+
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(requestIDFromContextMiddleware("X-Request-ID", requestID, true)),
+	)
+
+	inbound := context.WithValue(context.Background(), requestIDKey{}, "req-inbound-9c1d")
+	c.Get(inbound, "/inventory")
+	c.Get(inbound, "/pricing")
+	c.Get(context.Background(), "/cron") // no inbound request: generated
+
+	fmt.Printf("  ✓ same ID on both calls: %q, %q\n", got[0], got[1])
+	fmt.Printf("  ✓ generated: %q\n", got[2])
+}
+
+// [12] Prometheus metrics — count, latency and in-flight per host.
+func examplePrometheusMetrics() {
+	fmt.Println("\n[12] Prometheus middleware — requests_total, duration, in-flight")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	reg := prometheus.NewRegistry()
+	// route: the first path segment, so /items/1 and /items/2 share a series.
+	route := func(req *http.Request, label string) string {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/"), "/")
+		return "/" + segment
+	}
+	metrics, err := prometheusMiddleware(reg, "shop", "upstream", route, "route")
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(metrics))
+
+	for i := range 3 {
+		c.Get(context.Background(), fmt.Sprintf("/items/%d", i))
+	}
+	c.Post(context.Background(), "/fail", httpx.WithJSONBody(map[string]int{"qty": 1}))
+
+	families, _ := reg.Gather()
+	for _, mf := range families {
+		for _, m := range mf.GetMetric() {
+			labels := make([]string, 0, len(m.GetLabel()))
+			for _, lp := range m.GetLabel() {
+				if lp.GetName() != "host" { // the test server port changes per run
+					labels = append(labels, lp.GetName()+"="+lp.GetValue())
+				}
+			}
+			sort.Strings(labels)
+			var value string
+			switch {
+			case m.GetCounter() != nil:
+				value = strconv.FormatFloat(m.GetCounter().GetValue(), 'f', -1, 64)
+			case m.GetGauge() != nil:
+				value = strconv.FormatFloat(m.GetGauge().GetValue(), 'f', -1, 64)
+			case m.GetHistogram() != nil:
+				value = fmt.Sprintf("count=%d", m.GetHistogram().GetSampleCount())
+			}
+			fmt.Printf("  ✓ %s{%s} %s\n", mf.GetName(), strings.Join(labels, ","), value)
+		}
+	}
+}
+
+// [13] Recovery — a panicking RoundTripper becomes an error.
+func exampleRecoveryMiddleware() {
+	fmt.Println("\n[13] Recovery middleware — panics below it are returned as errors")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	buggy := func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Path == "/boom" {
+				var m map[string]int
+				m["oops"]++ // nil map write
+			}
+			return next.RoundTrip(req)
+		})
+	}
+
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(recoveryMiddleware(nil), buggy),
+	)
+
+	_, err := c.Get(context.Background(), "/boom")
+	if err != nil {
+		first, _, _ := strings.Cut(err.Error(), "\n")
+		fmt.Printf("  ✓ recovered: %s\n", first)
+		fmt.Printf("    stack trace attached: %v\n", strings.Contains(err.Error(), "goroutine"))
+	}
+	resp, err := c.Get(context.Background(), "/ok")
+	if err == nil {
+		fmt.Printf("  ✓ next request unaffected: status=%d\n", resp.StatusCode())
+	}
+}
+
+// [14] Dump middleware — see the exact request and response on the wire.
+func exampleDumpMiddleware() {
+	fmt.Println("\n[14] Dump middleware — request/response as sent and received")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, `{"id":7}`)
+	}))
+	defer srv.Close()
+
+	var dump strings.Builder
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(dumpMiddleware(&dump, true)))
+	c.Post(context.Background(), "/orders", httpx.WithJSONBody(map[string]int{"qty": 2}))
+
+	for _, line := range strings.Split(strings.TrimRight(dump.String(), "\n"), "\n") {
+		if !strings.HasPrefix(line, "Date:") && !strings.HasPrefix(line, "Host:") {
+			fmt.Printf("  %s\n", strings.TrimRight(line, "\r"))
+		}
+	}
+}
+
+// [15] Conditional middleware — auth everywhere except the health check.
+func exampleConditionalMiddleware() {
+	fmt.Println("\n[15] Conditional middleware — skip auth for /health")
+
+	got := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got[r.URL.Path] = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	notHealth := func(req *http.Request) bool { return req.URL.Path != "/health" }
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(conditionalMiddleware(notHealth,
+			httpx.HeaderInjector(map[string]string{"Authorization": "Bearer s3cr3t"}))),
+	)
+
+	c.Get(context.Background(), "/health")
+	c.Get(context.Background(), "/accounts")
+
+	fmt.Printf("  ✓ /health   Authorization=%q\n", got["/health"])
+	fmt.Printf("  ✓ /accounts Authorization=%q\n", got["/accounts"])
+}
+
+// [16] Max body size — refuse oversized responses.
+func exampleMaxBodySize() {
+	fmt.Println("\n[16] Max body size — 1 KiB limit")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small":
+			fmt.Fprint(w, `{"ok":true}`)
+		case "/large": // Content-Length set: rejected before reading
+			w.Header().Set("Content-Length", "4096")
+			w.Write(bytes.Repeat([]byte("x"), 4096))
+		case "/stream": // chunked, no Content-Length: rejected while reading
+			for range 8 {
+				w.Write(bytes.Repeat([]byte("y"), 512))
+				w.(http.Flusher).Flush()
+			}
+		}
+	}))
+	defer srv.Close()
+
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(maxBodySizeMiddleware(1024)))
+
+	for _, path := range []string{"/small", "/large", "/stream"} {
+		resp, err := c.Get(context.Background(), path)
+		switch {
+		case errors.Is(err, errBodyTooLarge):
+			fmt.Printf("  ✓ %-7s rejected: %v\n", path, err)
+		case err != nil:
+			fmt.Printf("  ✗ %-7s %v\n", path, err)
+		default:
+			fmt.Printf("  ✓ %-7s %d bytes\n", path, len(resp.Bytes()))
+		}
+	}
+}
+
+// [17] User-Agent — consistent product tokens, overridable per request.
+func exampleUserAgent() {
+	fmt.Println("\n[17] User-Agent middleware")
+
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get("User-Agent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	lib, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(defaultUserAgent(httpxVersion())))
+	lib.Get(context.Background(), "/")
+	fmt.Printf("  ✓ default:  %s\n", got)
+
+	app, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(userAgentMiddleware("MyApp/%s", "1.2.3")))
+	app.Get(context.Background(), "/")
+	fmt.Printf("  ✓ app:      %s\n", got)
+
+	req, _ := app.NewRequest(context.Background(), "GET", "/").
+		Header("User-Agent", "MyApp-Backfill/1.0").
+		Build()
+	app.Do(req)
+	fmt.Printf("  ✓ override: %s\n", got)
+}
+
 // ---
 
+// logger is the subset of *slog.Logger used by loggingMiddleware.
+type logger interface {
+	Info(msg string, args ...any)
+}
+
+// loggingConfig controls what loggingMiddleware records.
+type loggingConfig struct {
+	RedactHeaders []string // request headers logged as "[REDACTED]"
+	LogBodies     bool     // log request and response bodies
+	MaxBodyBytes  int      // body bytes logged; zero means 1024
+	MinStatus     int      // skip responses below this status; errors are always logged
+}
+
+// loggingMiddleware logs method, URL, status, duration, request ID and
+// request headers for every request. Logged bodies are read without
+// consuming them: the caller still sees the full payload.
+func loggingMiddleware(l logger, cfg loggingConfig) httpx.Middleware {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 1024
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+
+			args := []any{
+				"method", req.Method,
+				"url", req.URL.String(),
+				"duration", time.Since(start).Round(time.Millisecond),
+				"request_id", req.Header.Get("X-Request-ID"),
+				"headers", redactHeaders(req.Header, cfg.RedactHeaders),
+			}
+			if err != nil {
+				l.Info("http request failed", append(args, "error", err)...)
+				return nil, err
+			}
+			if resp.StatusCode < cfg.MinStatus {
+				return resp, nil
+			}
+			args = append(args, "status", resp.StatusCode)
+			if cfg.LogBodies {
+				if req.GetBody != nil {
+					if body, err := req.GetBody(); err == nil {
+						b, _ := io.ReadAll(io.LimitReader(body, int64(cfg.MaxBodyBytes)))
+						body.Close()
+						args = append(args, "request_body", string(b))
+					}
+				}
+				var b []byte
+				b, resp.Body = peekBody(resp.Body, cfg.MaxBodyBytes)
+				args = append(args, "response_body", string(b))
+			}
+			l.Info("http request", args...)
+			return resp, nil
+		})
+	}
+}
+
+// redactHeaders returns a copy of h with the named headers masked.
+func redactHeaders(h http.Header, names []string) http.Header {
+	out := h.Clone()
+	for _, name := range names {
+		if out.Get(name) != "" {
+			out.Set(name, "[REDACTED]")
+		}
+	}
+	return out
+}
+
+// peekBody reads up to n bytes of body and returns them together with a
+// replacement body that still yields the complete stream.
+func peekBody(body io.ReadCloser, n int) ([]byte, io.ReadCloser) {
+	b, _ := io.ReadAll(io.LimitReader(body, int64(n)))
+	return b, struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(b), body), body}
+}
+
+// gzipRequestMiddleware gzip-compresses request bodies of at least minSize
+// bytes at the given compress/gzip level and asks for compressed responses.
+// The body is buffered to learn its size, so the compressed request carries
+// an exact Content-Length and can be replayed through GetBody.
+//
+// Accept-Encoding: gzip is set on every request that lacks one, whatever its
+// size. That turns off net/http's transparent decompression, so this
+// middleware must be paired with decompressionMiddleware; otherwise callers
+// get gzip-encoded response bodies.
+func gzipRequestMiddleware(minSize, level int) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Accept-Encoding") == "" {
+				req = req.Clone(req.Context())
+				req.Header.Set("Accept-Encoding", "gzip")
+			}
+			if req.Body == nil || req.Body == http.NoBody || req.Header.Get("Content-Encoding") != "" {
+				return next.RoundTrip(req)
+			}
+
+			body, err := io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			out := req.Clone(req.Context())
+			if len(body) < minSize {
+				out.Body = io.NopCloser(bytes.NewReader(body))
+				out.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+				out.ContentLength = int64(len(body))
+				return next.RoundTrip(out)
+			}
+
+			var buf bytes.Buffer
+			zw, err := gzip.NewWriterLevel(&buf, level)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := zw.Write(body); err != nil {
+				return nil, err
+			}
+			if err := zw.Close(); err != nil {
+				return nil, err
+			}
+			compressed := buf.Bytes()
+
+			out.Body = io.NopCloser(bytes.NewReader(compressed))
+			out.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(compressed)), nil }
+			out.ContentLength = int64(len(compressed))
+			out.Header.Set("Content-Encoding", "gzip")
+			return next.RoundTrip(out)
+		})
+	}
+}
+
+// decompressionMiddleware decodes gzip and deflate response bodies, then
+// drops Content-Encoding and Content-Length, which no longer apply.
+func decompressionMiddleware() httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil || resp.Body == nil {
+				return resp, err
+			}
+
+			var zr io.ReadCloser
+			switch strings.ToLower(resp.Header.Get("Content-Encoding")) {
+			case "gzip", "x-gzip":
+				zr, err = gzip.NewReader(resp.Body)
+			case "deflate":
+				zr, err = zlib.NewReader(resp.Body)
+			default:
+				return resp, nil
+			}
+			if err != nil {
+				resp.Body.Close()
+				return nil, fmt.Errorf("decompress %s response: %w", resp.Header.Get("Content-Encoding"), err)
+			}
+
+			body := resp.Body
+			resp.Body = struct {
+				io.Reader
+				io.Closer
+			}{zr, closerFunc(func() error {
+				zr.Close()
+				return body.Close()
+			})}
+			resp.Header.Del("Content-Encoding")
+			resp.Header.Del("Content-Length")
+			resp.ContentLength = -1
+			resp.Uncompressed = true
+			return resp, nil
+		})
+	}
+}
+
+// requestIDFromContextMiddleware sets header to the ID extractor finds in
+// the request context. When there is none and autoGenerate is true, a
+// random UUID is used instead. A header already on the request is kept.
+func requestIDFromContextMiddleware(header string, extractor func(context.Context) string, autoGenerate bool) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(header) != "" {
+				return next.RoundTrip(req)
+			}
+			id := extractor(req.Context())
+			if id == "" && autoGenerate {
+				id = uuid.NewString()
+			}
+			if id == "" {
+				return next.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set(header, id)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// prometheusMiddleware registers client metrics with reg and returns a
+// middleware recording them:
+//
+//   - <ns>_<sub>_requests_total{method,code,host,labels...}           counter
+//   - <ns>_<sub>_request_duration_seconds{method,code,host,labels...} histogram
+//   - <ns>_<sub>_in_flight_requests                                   gauge
+//
+// Each extra label's value is extract(req, label); a nil extract reads the
+// request header named label. Transport errors are recorded with code "error".
+func prometheusMiddleware(reg prometheus.Registerer, namespace, subsystem string,
+	extract func(req *http.Request, label string) string, labels ...string) (httpx.Middleware, error) {
+	if extract == nil {
+		extract = func(req *http.Request, label string) string { return req.Header.Get(label) }
+	}
+	names := append([]string{"method", "code", "host"}, labels...)
+	requests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "requests_total",
+		Help:      "Outgoing HTTP requests by method, status code and host.",
+	}, names)
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "request_duration_seconds",
+		Help:      "Outgoing HTTP request latency.",
+		Buckets:   prometheus.DefBuckets,
+	}, names)
+	inFlight := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: subsystem,
+		Name:      "in_flight_requests",
+		Help:      "Outgoing HTTP requests currently in flight.",
+	})
+	for _, c := range []prometheus.Collector{requests, duration, inFlight} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			inFlight.Inc()
+			defer inFlight.Dec()
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			code := "error"
+			if err == nil {
+				code = strconv.Itoa(resp.StatusCode)
+			}
+			values := []string{req.Method, code, req.URL.Host}
+			for _, label := range labels {
+				values = append(values, extract(req, label))
+			}
+			requests.WithLabelValues(values...).Inc()
+			duration.WithLabelValues(values...).Observe(time.Since(start).Seconds())
+			return resp, err
+		})
+	}, nil
+}
+
+// recoveryMiddleware recovers panics raised by the middleware and transport
+// below it and returns handler(p) as the request error. A nil handler
+// reports the panic value with its stack trace.
+func recoveryMiddleware(handler func(p any) error) httpx.Middleware {
+	if handler == nil {
+		handler = func(p any) error {
+			return fmt.Errorf("panic in transport chain: %v\n%s", p, debug.Stack())
+		}
+	}
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (resp *http.Response, err error) {
+			defer func() {
+				if p := recover(); p != nil {
+					resp, err = nil, handler(p)
+				}
+			}()
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// dumpMiddleware writes every request and its response to w in HTTP wire
+// format, separated by marker lines. Bodies are included only when dumpBody
+// is true; either way they remain readable by the transport and the caller.
+func dumpMiddleware(w io.Writer, dumpBody bool) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if b, err := httputil.DumpRequestOut(req, dumpBody); err == nil {
+				fmt.Fprintf(w, ">>> request\n%s\n", b)
+			}
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				fmt.Fprintf(w, "<<< error: %v\n", err)
+				return nil, err
+			}
+			if b, err := httputil.DumpResponse(resp, dumpBody); err == nil {
+				fmt.Fprintf(w, "<<< response\n%s\n", b)
+			}
+			return resp, nil
+		})
+	}
+}
+
+// conditionalMiddleware applies mw only to requests matching predicate;
+// other requests go straight to the next transport.
+func conditionalMiddleware(predicate func(*http.Request) bool, mw httpx.Middleware) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		wrapped := mw(next)
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if predicate(req) {
+				return wrapped.RoundTrip(req)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// errBodyTooLarge is returned when a response exceeds maxBodySizeMiddleware's limit.
+var errBodyTooLarge = errors.New("response body too large")
+
+// maxBodySizeMiddleware fails responses larger than maxBytes. A declared
+// Content-Length over the limit fails at once without reading the body;
+// otherwise reading fails as soon as the limit is crossed.
+func maxBodySizeMiddleware(maxBytes int64) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return nil, err
+			}
+			if resp.ContentLength > maxBytes {
+				resp.Body.Close()
+				return nil, fmt.Errorf("%w: Content-Length %d exceeds %d bytes", errBodyTooLarge, resp.ContentLength, maxBytes)
+			}
+			resp.Body = &limitedBody{
+				r:     &io.LimitedReader{R: resp.Body, N: maxBytes + 1},
+				c:     resp.Body,
+				limit: maxBytes,
+			}
+			return resp, nil
+		})
+	}
+}
+
+// limitedBody reads at most limit bytes and errors instead of returning
+// more. The extra byte allowed by r tells "exactly limit" from "over"; once
+// over, every later Read returns the same error.
+type limitedBody struct {
+	r     *io.LimitedReader
+	c     io.Closer
+	limit int64
+	read  int64
+	err   error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	n, err := b.r.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		b.err = fmt.Errorf("%w: more than %d bytes", errBodyTooLarge, b.limit)
+		return n - int(b.read-b.limit), b.err
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error { return b.c.Close() }
+
+// userAgentMiddleware sets User-Agent to format filled with the app version,
+// followed by the httpx and Go versions, e.g.
+// "MyApp/1.2.3 httpx/0.5.0 Go/1.24.0". A User-Agent already set on the
+// request wins.
+func userAgentMiddleware(format, version string) httpx.Middleware {
+	return setUserAgent(fmt.Sprintf(format, version) + " " + libraryUserAgent(httpxVersion()))
+}
+
+// defaultUserAgent identifies the httpx library itself: "httpx/<version> Go/<go>".
+func defaultUserAgent(version string) httpx.Middleware {
+	return setUserAgent(libraryUserAgent(version))
+}
+
+// httpxVersion is the version of the httpx module linked into the binary,
+// without the "v", from the build info. It is "devel" when the module is
+// replaced by a local directory or the build info is unavailable.
+func httpxVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "devel"
+	}
+	for _, dep := range info.Deps {
+		if dep.Path != "github.com/n0l3r/httpx" {
+			continue
+		}
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+		if dep.Version == "" || dep.Version == "(devel)" {
+			return "devel"
+		}
+		return strings.TrimPrefix(dep.Version, "v")
+	}
+	return "devel"
+}
+
+// libraryUserAgent is the "httpx/<version> Go/<go>" product token pair.
+func libraryUserAgent(version string) string {
+	return "httpx/" + version + " Go/" + strings.TrimPrefix(runtime.Version(), "go")
+}
+
+// setUserAgent sets User-Agent to ua on requests that do not carry one.
+func setUserAgent(ua string) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("User-Agent") != "" {
+				return next.RoundTrip(req)
+			}
+			req = req.Clone(req.Context())
+			req.Header.Set("User-Agent", ua)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// closerFunc adapts a function to io.Closer.
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
 func unique(ss []string) []string {
 	seen := map[string]struct{}{}
 	var out []string
@@ -2,6 +2,13 @@
 // - GlobalRateLimiter (in-process token bucket)
 // - PerHostRateLimiter (per-host token bucket)
 // - Rate limiter + context cancellation
+// - Per-path limits (longest matching prefix wins)
+// - Custom limiter key (per tenant, per user, …)
+// - Adaptive limiter that backs off on 429 responses
+// - Tokens(key) introspection for back-pressure
+// - Per-request limiter bypass for health probes and admin calls
+// - Distributed token bucket in Redis shared by several clients
+// - Changing a host's limit at runtime
 package ratelimiter
 
 import (
@@ -9,11 +16,15 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/alicebob/miniredis/v2"
 	"github.com/n0l3r/httpx"
+	"github.com/redis/go-redis/v9"
 	"golang.org/x/time/rate"
 )
 
@@ -27,6 +38,13 @@ func Run() {
 	examplePerHostRateLimiter()
 	exampleRateLimiterThroughput()
 	exampleRateLimiterContextCancel()
+	examplePerPathRateLimiter()
+	exampleRateLimiterKey()
+	exampleAdaptiveRateLimiter()
+	exampleLimiterTokens()
+	exampleRateLimiterBypass()
+	exampleRedisRateLimiter()
+	exampleSetLimit()
 }
 
 // [1] GlobalRateLimiter — all requests share one limit.
@@ -162,3 +180,516 @@ func exampleRateLimiterContextCancel() {
 		fmt.Printf("  ✓ Rate limiter blocked, context cancelled: %v\n", err)
 	}
 }
+
+// [5] Per-path limits — endpoint quotas on a single host.
+func examplePerPathRateLimiter() {
+	fmt.Println("\n[5] Per-path limiter — /search 2 req/s, /items 20 req/s, /items/export 1 req/s")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rl := newPerPathLimiter(rate.Limit(50), 10, map[string]*rate.Limiter{
+		"/search":       rate.NewLimiter(rate.Limit(2), 1),
+		"/items":        rate.NewLimiter(rate.Limit(20), 1),
+		"/items/export": rate.NewLimiter(rate.Limit(1), 1), // longer prefix wins over /items
+	})
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(rateLimitMiddleware(rl)))
+
+	for _, path := range []string{"/search?q=go", "/items/1", "/items/export"} {
+		start := time.Now()
+		for range 3 {
+			c.Get(context.Background(), path)
+		}
+		fmt.Printf("  ✓ 3× %-14s in %v\n", path, time.Since(start).Round(10*time.Millisecond))
+	}
+}
+
+// [6] Custom limiter key — one bucket per tenant instead of per host.
+func exampleRateLimiterKey() {
+	fmt.Println("\n[6] Custom key — per-tenant buckets from the X-Tenant-ID header")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rl := newKeyedLimiter(rate.Limit(20), 5,
+		map[string]*rate.Limiter{"tenant-a": rate.NewLimiter(rate.Limit(2), 1)},
+		withLimiterKey(func(req *http.Request) string { return req.Header.Get("X-Tenant-ID") }),
+	)
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(rateLimitMiddleware(rl)))
+
+	send := func(tenant string, n int) time.Duration {
+		start := time.Now()
+		var wg sync.WaitGroup
+		for range n {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req, err := c.NewRequest(context.Background(), "GET", "/reports").
+					Header("X-Tenant-ID", tenant).
+					Build()
+				if err == nil {
+					c.Do(req)
+				}
+			}()
+		}
+		wg.Wait()
+		return time.Since(start).Round(10 * time.Millisecond)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		aTook time.Duration
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		aTook = send("tenant-a", 3) // 2 req/s: throttled
+	}()
+	bTook := send("tenant-b", 5) // own bucket: unaffected by tenant-a's burst
+	wg.Wait()
+
+	fmt.Printf("  ✓ tenant-a 3 requests in %v\n", aTook)
+	fmt.Printf("  ✓ tenant-b 5 requests in %v\n", bTook)
+}
+
+// [7] Adaptive limiter — halve the rate on 429, creep back up on success.
+func exampleAdaptiveRateLimiter() {
+	fmt.Println("\n[7] Adaptive limiter — starts at 40 req/s, server rejects the first 3 calls")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 3 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rl := newAdaptiveLimiter(rate.Limit(40), 1)
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(rateLimitMiddleware(rl)))
+
+	for i := range 8 {
+		resp, err := c.Get(context.Background(), "/quota")
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			continue
+		}
+		fmt.Printf("  call #%d → %d, limit now %.1f req/s\n", i+1, resp.StatusCode(), float64(rl.Limit()))
+	}
+}
+
+// [8] Tokens(key) — shed optional work before it would block.
+func exampleLimiterTokens() {
+	fmt.Println("\n[8] Tokens(key) — 1 req/s, burst 3")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rl := newKeyedLimiter(rate.Limit(1), 3, nil)
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(rateLimitMiddleware(rl)))
+	host := srv.Listener.Addr().String()
+
+	fmt.Printf("  before:          %.2f tokens\n", rl.Tokens(host))
+	for range 2 {
+		c.Get(context.Background(), "/orders")
+	}
+	fmt.Printf("  after 2 calls:   %.2f tokens\n", rl.Tokens(host))
+	c.Get(context.Background(), "/orders")
+
+	// Back-pressure: skip the optional prefetch instead of waiting a second.
+	if rl.Tokens(host) < 1 {
+		fmt.Printf("  ✓ %.2f tokens left — optional prefetch skipped\n", rl.Tokens(host))
+	}
+}
+
+// [9] Bypass — health probes skip the limiter, business calls don't.
+func exampleRateLimiterBypass() {
+	fmt.Println("\n[9] Bypass — limiter at 1 req/s, 10 concurrent bypassed probes")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	rl := newKeyedLimiter(rate.Limit(1), 1, nil)
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(rateLimitMiddleware(rl)))
+
+	c.Get(context.Background(), "/orders") // drains the only token
+
+	probeCtx := withRateLimitBypass(context.Background())
+	start := time.Now()
+	var (
+		wg sync.WaitGroup
+		ok atomic.Int32
+	)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp, err := c.Get(probeCtx, "/healthz"); err == nil && resp.IsSuccess() {
+				ok.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("  ✓ %d/10 bypassed probes in %v\n", ok.Load(), time.Since(start).Round(time.Millisecond))
+
+	start = time.Now()
+	c.Get(context.Background(), "/orders")
+	fmt.Printf("  ✓ next regular call still throttled: waited %v\n", time.Since(start).Round(100*time.Millisecond))
+}
+
+// [10] Redis limiter — one aggregate limit across every replica.
+func exampleRedisRateLimiter() {
+	fmt.Println("\n[10] Redis token bucket — 10 req/s shared by 2 clients")
+
+	// miniredis is an in-process Redis server, so no external service is needed.
+	mr, err := miniredis.Run()
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	defer mr.Close()
+
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer rdb.Close()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	// Each client stands in for one replica with its own limiter instance.
+	replica := func() *httpx.Client {
+		rl := newRedisLimiter(rdb, "ratelimit:partner-api", rate.Limit(10), 2)
+		c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(rateLimitMiddleware(rl)))
+		return c
+	}
+	replicas := []*httpx.Client{replica(), replica()}
+
+	const perReplica = 6
+	start := time.Now()
+	var wg sync.WaitGroup
+	for _, c := range replicas {
+		for range perReplica {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.Get(context.Background(), "/partners")
+			}()
+		}
+	}
+	wg.Wait()
+
+	elapsed := time.Since(start)
+	fmt.Printf("  ✓ %d requests from %d clients in %v\n", calls.Load(), len(replicas), elapsed.Round(time.Millisecond))
+	fmt.Printf("    Aggregate RPS: %.1f (limit: 10, burst 2)\n", float64(calls.Load())/elapsed.Seconds())
+}
+
+// [11] SetLimit — a plan upgrade takes effect without rebuilding the client.
+func exampleSetLimit() {
+	fmt.Println("\n[11] SetLimit — change a host's limit at runtime")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	host := srv.Listener.Addr().String()
+
+	rl := newKeyedLimiter(rate.Limit(5), 1, nil)
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(rateLimitMiddleware(rl)))
+
+	send := func(n int) time.Duration {
+		start := time.Now()
+		for range n {
+			c.Get(context.Background(), "/")
+		}
+		return time.Since(start).Round(10 * time.Millisecond)
+	}
+
+	fmt.Printf("  ✓ 5 req/s:  4 requests in %v\n", send(4))
+
+	// This request waits on the old 5 req/s bucket; the update doesn't touch it.
+	inFlight := make(chan error, 1)
+	go func() {
+		_, err := c.Get(context.Background(), "/")
+		inFlight <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	rl.SetLimit(host, rate.Limit(100), 10)
+	fmt.Printf("  ✓ 100 req/s: 4 requests in %v\n", send(4))
+	fmt.Printf("  ✓ in-flight request on the old limiter finished, err=%v\n", <-inFlight)
+}
+
+// ---
+
+// limiter throttles outgoing requests. Wait blocks until req may be sent or
+// its context is done; Tokens reports the tokens currently available for
+// key, so callers can apply back-pressure instead of blocking.
+type limiter interface {
+	Wait(req *http.Request) error
+	Tokens(key string) float64
+}
+
+// responseObserver is implemented by limiters that adapt to responses.
+type responseObserver interface {
+	OnResponse(resp *http.Response)
+}
+
+// rateLimitBypassKey marks a context whose requests skip rateLimitMiddleware.
+type rateLimitBypassKey struct{}
+
+// withRateLimitBypass returns a context whose requests are sent without
+// waiting for, or consuming, a token.
+func withRateLimitBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, rateLimitBypassKey{}, true)
+}
+
+// rateLimitMiddleware applies l to every request before it is sent, and
+// reports responses back to l when it implements responseObserver.
+func rateLimitMiddleware(l limiter) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Context().Value(rateLimitBypassKey{}) != nil {
+				return next.RoundTrip(req)
+			}
+			if err := l.Wait(req); err != nil {
+				return nil, err
+			}
+			resp, err := next.RoundTrip(req)
+			if obs, ok := l.(responseObserver); ok && err == nil {
+				obs.OnResponse(resp)
+			}
+			return resp, err
+		})
+	}
+}
+
+// perPathLimiter applies per-endpoint quotas. Rules are keyed by URL path
+// prefix; the longest matching prefix wins and unmatched paths share one
+// default limiter.
+type perPathLimiter struct {
+	def   *rate.Limiter
+	rules map[string]*rate.Limiter
+}
+
+func newPerPathLimiter(defaultLimit rate.Limit, burst int, rules map[string]*rate.Limiter) *perPathLimiter {
+	return &perPathLimiter{def: rate.NewLimiter(defaultLimit, burst), rules: rules}
+}
+
+func (l *perPathLimiter) Wait(req *http.Request) error {
+	return l.limiterFor(req.URL.Path).Wait(req.Context())
+}
+
+// Tokens returns the tokens available for requests to path.
+func (l *perPathLimiter) Tokens(path string) float64 {
+	return l.limiterFor(path).Tokens()
+}
+
+func (l *perPathLimiter) limiterFor(path string) *rate.Limiter {
+	best, match := "", l.def
+	for prefix, rl := range l.rules {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(best) {
+			best, match = prefix, rl
+		}
+	}
+	return match
+}
+
+// keyedLimiterOption configures newKeyedLimiter.
+type keyedLimiterOption func(*keyedLimiter)
+
+// withLimiterKey replaces the default per-host key, e.g. to throttle per
+// tenant, per user or per host+path.
+func withLimiterKey(fn func(*http.Request) string) keyedLimiterOption {
+	return func(l *keyedLimiter) { l.key = fn }
+}
+
+// keyedLimiter keeps one token bucket per key. Keys listed in overrides use
+// their own limiter; any other key gets a fresh bucket at limit/burst.
+type keyedLimiter struct {
+	key   func(*http.Request) string
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newKeyedLimiter(limit rate.Limit, burst int, overrides map[string]*rate.Limiter, opts ...keyedLimiterOption) *keyedLimiter {
+	l := &keyedLimiter{
+		key:      func(req *http.Request) string { return req.URL.Host },
+		limit:    limit,
+		burst:    burst,
+		limiters: make(map[string]*rate.Limiter, len(overrides)),
+	}
+	for k, rl := range overrides {
+		l.limiters[k] = rl
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+func (l *keyedLimiter) Wait(req *http.Request) error {
+	return l.limiterFor(l.key(req)).Wait(req.Context())
+}
+
+// Tokens reports the tokens available for key without creating a bucket;
+// a key never seen has a full burst.
+func (l *keyedLimiter) Tokens(key string) float64 {
+	l.mu.Lock()
+	rl, ok := l.limiters[key]
+	l.mu.Unlock()
+	if !ok {
+		return float64(l.burst)
+	}
+	return rl.Tokens()
+}
+
+// SetLimit replaces the bucket for key with a new one at limit/burst.
+// Requests already waiting keep the old bucket; later requests use the new
+// one, which starts full.
+func (l *keyedLimiter) SetLimit(key string, limit rate.Limit, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limiters[key] = rate.NewLimiter(limit, burst)
+}
+
+func (l *keyedLimiter) limiterFor(key string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rl, ok := l.limiters[key]
+	if !ok {
+		rl = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[key] = rl
+	}
+	return rl
+}
+
+// adaptiveLimiter is a token bucket whose rate reacts to the server: every
+// 429 halves it, every success adds back a tenth of the initial rate, up
+// to the initial rate (additive increase, multiplicative decrease).
+type adaptiveLimiter struct {
+	rl      *rate.Limiter
+	initial rate.Limit
+	floor   rate.Limit
+
+	mu sync.Mutex
+}
+
+func newAdaptiveLimiter(initial rate.Limit, burst int) *adaptiveLimiter {
+	return &adaptiveLimiter{
+		rl:      rate.NewLimiter(initial, burst),
+		initial: initial,
+		floor:   initial / 32,
+	}
+}
+
+func (l *adaptiveLimiter) Wait(req *http.Request) error {
+	return l.rl.Wait(req.Context())
+}
+
+// Tokens ignores key: an adaptiveLimiter has a single bucket.
+func (l *adaptiveLimiter) Tokens(string) float64 { return l.rl.Tokens() }
+
+// Limit returns the current rate.
+func (l *adaptiveLimiter) Limit() rate.Limit { return l.rl.Limit() }
+
+func (l *adaptiveLimiter) OnResponse(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cur := l.rl.Limit()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		l.rl.SetLimit(max(cur/2, l.floor))
+	case resp.StatusCode < http.StatusBadRequest && cur < l.initial:
+		l.rl.SetLimit(min(cur+l.initial/10, l.initial))
+	}
+}
+
+// tokenBucketScript refills and takes one token atomically. It returns 0
+// when a token was taken, otherwise the milliseconds until one is available.
+// The clock is Redis's own TIME, so instances with skewed clocks still agree.
+var tokenBucketScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * limit)
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+else
+	wait = math.ceil((1 - tokens) / limit * 1000)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / limit * 1000) + 1000)
+return wait
+`)
+
+// redisLimiter is a token bucket stored in Redis, so every instance using
+// the same key shares one limit.
+type redisLimiter struct {
+	rdb   redis.UniversalClient
+	key   string
+	limit rate.Limit
+	burst int
+}
+
+func newRedisLimiter(rdb redis.UniversalClient, key string, limit rate.Limit, burst int) *redisLimiter {
+	return &redisLimiter{rdb: rdb, key: key, limit: limit, burst: burst}
+}
+
+func (l *redisLimiter) Wait(req *http.Request) error {
+	ctx := req.Context()
+	for {
+		wait, err := tokenBucketScript.Run(ctx, l.rdb, []string{l.key},
+			float64(l.limit), l.burst).Int64()
+		if err != nil {
+			return fmt.Errorf("redis limiter: %w", err)
+		}
+		if wait == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(wait) * time.Millisecond):
+		}
+	}
+}
+
+// Tokens ignores key: the bucket is the Redis key given at construction.
+// Elapsed time is measured on Redis's clock, like the script's.
+func (l *redisLimiter) Tokens(string) float64 {
+	ctx := context.Background()
+	state, err := l.rdb.HMGet(ctx, l.key, "tokens", "ts").Result()
+	if err != nil || state[0] == nil || state[1] == nil {
+		return float64(l.burst)
+	}
+	now, err := l.rdb.Time(ctx).Result()
+	if err != nil {
+		return float64(l.burst)
+	}
+	tokens, _ := strconv.ParseFloat(state[0].(string), 64)
+	ts, _ := strconv.ParseFloat(state[1].(string), 64)
+	elapsed := max(0, float64(now.UnixMilli())-ts) / 1000
+	return min(float64(l.burst), tokens+elapsed*float64(l.limit))
+}
@@ -5,14 +5,33 @@
 // - Exponential backoff, FullJitter, Constant, Linear
 // - OnRetry callback
 // - RetryOnlyIdempotent flag
+// - Honoring Retry-After (delay-seconds and HTTP-date)
+// - Retrying transient transport failures (reset, refused, EOF)
+// - Retry budget (cap on total elapsed time)
+// - Maximum retry delay cap across all backoff strategies
+// - Give-up callback when all attempts are exhausted
+// - Per-attempt timeout vs a deadline shared by all attempts
+// - Decorrelated jitter backoff
+// - Disabling retry for a single call
+// - Per-call retry policy override
+// - Reading the current attempt number from the request context
+// - Replayable request bodies for a transport that resends on its own
 package retry
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/n0l3r/httpx"
@@ -31,6 +50,18 @@ func Run() {
 	exampleExponentialBackoff()
 	exampleOnRetryCallback()
 	exampleRetryOnlyIdempotent()
+	exampleRetryAfterBackoff()
+	exampleRetryOnNetworkError()
+	exampleRetryBudget()
+	exampleMaxRetryDelay()
+	exampleOnGiveUp()
+	examplePerAttemptTimeout()
+	exampleDecorrelatedJitter()
+	exampleNoRetryForOneCall()
+	examplePerCallRetryPolicy()
+	exampleRetryOnErrors()
+	exampleRetryCount()
+	exampleBodyRewind()
 }
 
 // [1] Default retry policy — retries on network errors and 5xx.
@@ -153,6 +184,7 @@ func exampleExponentialBackoff() {
 		{"Exponential    ", httpx.ExponentialBackoff(100*time.Millisecond, 5*time.Second, 0.1)},
 		{"Constant (500ms)", httpx.ConstantBackoff(500 * time.Millisecond)},
 		{"Linear (100ms) ", httpx.LinearBackoff(100*time.Millisecond, 100*time.Millisecond)},
+		{"Linear (50+100ms)", httpx.LinearBackoff(50*time.Millisecond, 100*time.Millisecond)},
 	}
 
 	for _, s := range strategies {
@@ -224,3 +256,829 @@ func exampleRetryOnlyIdempotent() {
 	c.Get(context.Background(), "/resource")
 	fmt.Printf("  ✓ GET called %d time(s) (expected 3, with retry)\n", calls.Load())
 }
+
+// [8] Retry-After aware backoff — the server decides how long to wait.
+func exampleRetryAfterBackoff() {
+	fmt.Println("\n[8] Retry-After backoff — seconds and HTTP-date formats")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.Header().Set("Retry-After", time.Now().Add(time.Second).UTC().Format(http.TimeFormat))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 3:
+			w.WriteHeader(http.StatusServiceUnavailable) // no hint → fallback
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	fallback := httpx.ConstantBackoff(50 * time.Millisecond)
+	policy := &httpx.RetryPolicy{
+		MaxAttempts: 4,
+		Backoff:     httpx.ConstantBackoff(0), // retryAfterOnRetry waits instead
+		Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnStatus429, httpx.RetryOnStatus5xx},
+		OnRetry: retryAfterOnRetry(fallback, func(attempt int, req *http.Request, resp *http.Response, err error) {
+			statusCode, hint := 0, "none"
+			if resp != nil {
+				statusCode = resp.StatusCode
+				if v := resp.Header.Get("Retry-After"); v != "" {
+					hint = v
+				}
+			}
+			fmt.Printf("    → retry #%d  status=%d  Retry-After=%s\n", attempt, statusCode, hint)
+		}),
+	}
+
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithRetryPolicy(policy))
+
+	start := time.Now()
+	resp, err := c.Get(context.Background(), "/")
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	fmt.Printf("  ✓ status=%d after %d attempts in %v\n",
+		resp.StatusCode(), calls.Load(), time.Since(start).Round(100*time.Millisecond))
+}
+
+// [9] RetryOnNetworkError — transient transport failures are retried.
+func exampleRetryOnNetworkError() {
+	fmt.Println("\n[9] RetryOnNetworkError — dropped connections and transport errors")
+
+	// Server side: the first request has its connection closed mid-flight.
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	policy := &httpx.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     httpx.ConstantBackoff(0),
+		Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnNetworkError},
+		OnRetry: func(attempt int, req *http.Request, resp *http.Response, err error) {
+			fmt.Printf("    → retry #%d  err=%v\n", attempt, err)
+		},
+	}
+
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithRetryPolicy(policy))
+	resp, err := c.Get(context.Background(), "/")
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	fmt.Printf("  ✓ dropped connection: attempts=%d status=%d\n", calls.Load(), resp.StatusCode())
+
+	// Client side: a transport that fails with each transient error once.
+	transient := []error{syscall.ECONNREFUSED, syscall.ECONNRESET, io.ErrUnexpectedEOF}
+	var failures atomic.Int32
+	flaky := httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if n := int(failures.Add(1)); n <= len(transient) {
+			return nil, transient[n-1]
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})
+
+	policy.MaxAttempts = len(transient) + 1
+	calls.Store(1) // skip the hijack branch
+	c, _ = httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(flaky), httpx.WithRetryPolicy(policy))
+	resp, err = c.Get(context.Background(), "/")
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	fmt.Printf("  ✓ transport errors: attempts=%d status=%d\n", failures.Load(), resp.StatusCode())
+}
+
+// [10] Retry budget — stop retrying once the total time is spent.
+func exampleRetryBudget() {
+	fmt.Println("\n[10] Retry budget — 300ms total, slow failing server")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(80 * time.Millisecond)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	// The context deadline is the budget: it stops the retry loop and
+	// cancels an in-flight attempt. The backoff is clamped so it never
+	// sleeps past the deadline.
+	const budget = 300 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	policy := &httpx.RetryPolicy{
+		MaxAttempts: 10,
+		Backoff:     clampToDeadline(deadline, httpx.ConstantBackoff(50*time.Millisecond)),
+		Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnStatus5xx},
+	}
+
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithRetryPolicy(policy))
+
+	start := time.Now()
+	_, err := c.Get(ctx, "/")
+	fmt.Printf("  ✓ attempts=%d of %d in %v\n",
+		calls.Load(), policy.MaxAttempts, time.Since(start).Round(10*time.Millisecond))
+	fmt.Printf("    err=%v  timeout=%v\n", err, httpx.IsTimeout(err))
+}
+
+// [11] Maximum retry delay — no strategy ever sleeps longer than the cap.
+func exampleMaxRetryDelay() {
+	fmt.Println("\n[11] Max retry delay — every strategy capped at 1s")
+
+	const maxDelay = time.Second
+
+	strategies := []struct {
+		name string
+		bo   httpx.BackoffStrategy
+	}{
+		// ExponentialBackoff and FullJitterBackoff take their cap directly.
+		{"Exponential     ", httpx.ExponentialBackoff(100*time.Millisecond, maxDelay, 0)},
+		{"FullJitter      ", httpx.FullJitterBackoff(100*time.Millisecond, maxDelay)},
+		// Linear and Constant have no cap of their own — wrap them.
+		{"Linear (capped) ", capBackoff(httpx.LinearBackoff(300*time.Millisecond, 300*time.Millisecond), maxDelay)},
+		{"Constant (capped)", capBackoff(httpx.ConstantBackoff(5*time.Second), maxDelay)},
+	}
+
+	for _, s := range strategies {
+		delays := make([]string, 6)
+		exceeded := false
+		for i := range delays {
+			d := s.bo(i)
+			exceeded = exceeded || d > maxDelay
+			delays[i] = d.Round(time.Millisecond).String()
+		}
+		fmt.Printf("  %-18s attempt 0-5: %v  within cap: %v\n", s.name, delays, !exceeded)
+	}
+}
+
+// [12] Give-up callback — fires once when the policy runs out of attempts.
+func exampleOnGiveUp() {
+	fmt.Println("\n[12] Give-up callback — alert when retries are exhausted")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/down" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	policy := &httpx.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     httpx.ConstantBackoff(0),
+		Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnStatus5xx},
+	}
+
+	var gaveUp atomic.Int32
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithRetryPolicy(policy),
+		httpx.WithMiddleware(giveUpMiddleware(policy, func(req *http.Request, resp *http.Response, err error) {
+			gaveUp.Add(1)
+			statusCode := 0
+			if resp != nil {
+				statusCode = resp.StatusCode
+			}
+			fmt.Printf("    ⚠ gave up on %s %s  last status=%d err=%v\n", req.Method, req.URL.Path, statusCode, err)
+		})),
+	)
+
+	// Concurrent calls on one parent context each get their own counter.
+	var wg sync.WaitGroup
+	for _, path := range []string{"/down", "/up", "/down"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Get(withGiveUp(context.Background()), path)
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("  ✓ 3 concurrent calls, server saw %d request(s)\n", calls.Load())
+	fmt.Printf("    give-up callback fired %d time(s) (expected 2)\n", gaveUp.Load())
+}
+
+// [13] Per-attempt timeout — reset the deadline for every retry.
+func examplePerAttemptTimeout() {
+	fmt.Println("\n[13] Per-attempt timeout vs shared deadline (50ms, server takes 30ms)")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		time.Sleep(30 * time.Millisecond)
+		if n%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	policy := &httpx.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     httpx.ConstantBackoff(0),
+		Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnStatus5xx},
+	}
+
+	// Shared: one 50ms context deadline bleeds across all three attempts.
+	shared, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithRetryPolicy(policy))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := shared.Get(ctx, "/")
+	fmt.Printf("  shared deadline:     attempts=%d err=%v\n", calls.Load(), err)
+
+	// Per attempt: TimeoutMiddleware lives in the transport chain, so every
+	// attempt starts with a fresh 50ms budget.
+	calls.Store(0)
+	perAttempt, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithRetryPolicy(policy),
+		httpx.WithMiddleware(httpx.TimeoutMiddleware(50*time.Millisecond)),
+	)
+	resp, err := perAttempt.Get(context.Background(), "/")
+	if err != nil {
+		fmt.Printf("  ✗ per-attempt timeout: %v\n", err)
+		return
+	}
+	fmt.Printf("  ✓ per-attempt timeout: attempts=%d status=%d\n", calls.Load(), resp.StatusCode())
+}
+
+// [14] Decorrelated jitter — spreads retries more than full jitter.
+func exampleDecorrelatedJitter() {
+	fmt.Println("\n[14] Decorrelated jitter backoff — base 100ms, cap 2s")
+
+	const (
+		base     = 100 * time.Millisecond
+		maxDelay = 2 * time.Second
+		attempts = 6
+		runs     = 200
+	)
+
+	bo := decorrelatedJitterBackoff(base, maxDelay)
+
+	delays := make([]string, attempts)
+	for i := range delays {
+		delays[i] = bo(i).Round(time.Millisecond).String()
+	}
+	fmt.Printf("  sample run attempt 0-5: %v\n", delays)
+
+	// Average per attempt over many runs, checking the [base, max] bound.
+	var sums [attempts]time.Duration
+	inBounds := true
+	for range runs {
+		for i := range attempts {
+			d := bo(i)
+			inBounds = inBounds && d >= base && d <= maxDelay
+			sums[i] += d
+		}
+	}
+	avgs := make([]string, attempts)
+	for i, sum := range sums {
+		avgs[i] = (sum / runs).Round(time.Millisecond).String()
+	}
+	fmt.Printf("  average attempt 0-5:    %v\n", avgs)
+	fmt.Printf("  ✓ all delays within [%v, %v]: %v\n", base, maxDelay, inBounds)
+
+	// One strategy shared by many goroutines — state is mutex-guarded.
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range attempts {
+				bo(i)
+			}
+		}()
+	}
+	wg.Wait()
+	fmt.Println("  ✓ 50 goroutines shared one strategy safely")
+}
+
+// [15] No retry for one call — keep the shared policy untouched.
+func exampleNoRetryForOneCall() {
+	fmt.Println("\n[15] Disable retry for a single call")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	// Both clients share one transport (and its connection pool); only the
+	// retrying client carries the policy. Nothing mutates the policy.
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	defer transport.CloseIdleConnections()
+
+	policy := &httpx.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     httpx.ConstantBackoff(0),
+		Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnStatus5xx},
+	}
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(transport), httpx.WithRetryPolicy(policy))
+	noRetry, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(transport))
+
+	c.Get(context.Background(), "/report")
+	fmt.Printf("  ✓ default call:  server called %d time(s) (expected 3)\n", calls.Load())
+
+	calls.Store(0)
+	noRetry.Get(context.Background(), "/report/side-effect")
+	fmt.Printf("  ✓ no-retry call: server called %d time(s) (expected 1)\n", calls.Load())
+}
+
+// [16] Per-call retry policy — different call sites, different policies.
+func examplePerCallRetryPolicy() {
+	fmt.Println("\n[16] Per-call retry policy override")
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls[r.URL.Path]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	defer transport.CloseIdleConnections()
+
+	attempts := func(n int) *httpx.RetryPolicy {
+		return &httpx.RetryPolicy{
+			MaxAttempts: n,
+			Backoff:     httpx.ConstantBackoff(0),
+			Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnStatus5xx},
+		}
+	}
+	var (
+		defaultPolicy = attempts(3)
+		healthPolicy  = attempts(1)
+		paymentPolicy = attempts(5)
+	)
+
+	clients := &policyClients{opts: []httpx.Option{
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithTransport(transport),
+	}}
+
+	for _, call := range []struct {
+		path   string
+		policy *httpx.RetryPolicy
+	}{
+		{"/orders", defaultPolicy},
+		{"/health", healthPolicy},
+		{"/payments/42", paymentPolicy},
+	} {
+		c, err := clients.get(call.policy)
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			return
+		}
+		c.Get(context.Background(), call.path)
+		mu.Lock()
+		fmt.Printf("  ✓ %-14s server called %d time(s) (MaxAttempts=%d)\n",
+			call.path, calls[call.path], call.policy.MaxAttempts)
+		mu.Unlock()
+	}
+}
+
+// [17] RetryOnErrors — retry specific errors only, next to status conditions.
+func exampleRetryOnErrors() {
+	fmt.Println("\n[17] RetryOnErrors — retry DNS hiccups, not certificate errors")
+
+	var (
+		errTempDNS = errors.New("dns: temporary failure in name resolution")
+		errBadCert = errors.New("tls: certificate signed by unknown authority")
+	)
+
+	var mu sync.Mutex
+	calls := map[string]int{}
+	transport := httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		calls[req.URL.Path]++
+		n := calls[req.URL.Path]
+		mu.Unlock()
+		switch {
+		case req.URL.Path == "/dns" && n == 1:
+			return nil, fmt.Errorf("dial api.internal: %w", errTempDNS)
+		case req.URL.Path == "/cert":
+			return nil, fmt.Errorf("handshake: %w", errBadCert)
+		case req.URL.Path == "/busy" && n == 1:
+			return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Header: http.Header{}, Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: http.Header{}, Request: req}, nil
+	})
+
+	policy := &httpx.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     httpx.ConstantBackoff(0),
+		Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnErrors(errTempDNS), httpx.RetryOnStatus5xx},
+	}
+	c, _ := httpx.New(
+		httpx.WithBaseURL("http://api.internal"),
+		httpx.WithTransport(transport),
+		httpx.WithRetryPolicy(policy),
+	)
+
+	for _, path := range []string{"/dns", "/cert", "/busy"} {
+		_, err := c.Get(context.Background(), path)
+		mu.Lock()
+		n := calls[path]
+		mu.Unlock()
+		fmt.Printf("  ✓ %-6s attempts=%d  err=%v\n", path, n, err)
+	}
+}
+
+// [18] Retry count — middleware learns which attempt it is running in.
+func exampleRetryCount() {
+	fmt.Println("\n[18] retryCount(ctx) — per-attempt behavior in middleware")
+
+	var mu sync.Mutex
+	var seen []string
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("X-Retry-Attempt"))
+		mu.Unlock()
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	markRetries := func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			if isRetry(ctx) {
+				req = req.Clone(ctx)
+				req.Header.Set("X-Retry-Attempt", strconv.Itoa(retryCount(ctx)))
+				fmt.Printf("    ⚠ retry %d for %s\n", retryCount(ctx), req.URL.Path)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+
+	policy := &httpx.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     httpx.ConstantBackoff(0),
+		Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnStatus5xx},
+	}
+	c, _ := httpx.New(
+		httpx.WithBaseURL(srv.URL),
+		httpx.WithRetryPolicy(policy),
+		// Compose by hand so counting runs before the middleware that reads it.
+		httpx.WithMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return retryCountMiddleware()(markRetries(next))
+		}),
+	)
+
+	resp, err := c.Get(withRetryCount(context.Background()), "/sync")
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	fmt.Printf("  ✓ status=%d, X-Retry-Attempt per attempt: %q\n", resp.StatusCode(), seen)
+}
+
+// [19] Body rewind — httpx's retry replays the body between attempts by
+// itself; a transport underneath that resends on its own needs GetBody.
+func exampleBodyRewind() {
+	fmt.Println("\n[19] rewindBodyMiddleware — replayable bodies for a resending transport")
+
+	var mu sync.Mutex
+	var received []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, string(body))
+		mu.Unlock()
+		if r.Header.Get("X-Token") != "fresh" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	policy := &httpx.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     httpx.ConstantBackoff(0),
+		Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnStatus5xx},
+	}
+	payload := []byte(`{"event":"signup","user":42}`)
+	for _, tc := range []struct {
+		name string
+		opts []httpx.Option
+	}{
+		{"without middleware", nil},
+		{"with middleware", []httpx.Option{httpx.WithMiddleware(rewindBodyMiddleware(rewindBody))}},
+	} {
+		mu.Lock()
+		received = nil
+		mu.Unlock()
+
+		opts := append([]httpx.Option{
+			httpx.WithRetryPolicy(policy),
+			httpx.WithTransport(&tokenRefreshTransport{base: http.DefaultTransport}),
+		}, tc.opts...)
+		c, _ := httpx.New(opts...)
+
+		body := io.MultiReader(bytes.NewReader(payload)) // no GetBody, no Seek
+		req, _ := http.NewRequestWithContext(context.Background(), http.MethodPost, srv.URL+"/events", body)
+		resp, err := c.Do(req)
+		if err != nil {
+			fmt.Printf("  ✗ %s: %v\n", tc.name, err)
+			continue
+		}
+		mu.Lock()
+		intact := len(received) > 0
+		for _, b := range received {
+			intact = intact && b == string(payload)
+		}
+		fmt.Printf("  ✓ %-18s status=%d, %d sends, full body every time: %v\n", tc.name, resp.StatusCode(), len(received), intact)
+		mu.Unlock()
+	}
+}
+
+// tokenRefreshTransport answers a 401 by sending the request once more with
+// a fresh X-Token. The resend needs GetBody; without one the 401 is returned.
+type tokenRefreshTransport struct {
+	base http.RoundTripper
+}
+
+func (t *tokenRefreshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if hasBody && req.GetBody == nil {
+		return resp, nil
+	}
+	out := req.Clone(req.Context())
+	if hasBody {
+		if out.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	out.Header.Set("X-Token", "fresh")
+	resp.Body.Close()
+	return t.base.RoundTrip(out)
+}
+
+// ---
+
+// policyClients hands out one client per retry policy. All clients share
+// the same base options — including the transport — so overriding the
+// policy for a call does not cost a new connection pool.
+type policyClients struct {
+	opts []httpx.Option
+
+	mu      sync.Mutex
+	clients map[*httpx.RetryPolicy]*httpx.Client
+}
+
+func (p *policyClients) get(policy *httpx.RetryPolicy) (*httpx.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[policy]; ok {
+		return c, nil
+	}
+	c, err := httpx.New(append(slices.Clip(p.opts), httpx.WithRetryPolicy(policy))...)
+	if err != nil {
+		return nil, err
+	}
+	if p.clients == nil {
+		p.clients = make(map[*httpx.RetryPolicy]*httpx.Client)
+	}
+	p.clients[policy] = c
+	return c, nil
+}
+
+// decorrelatedJitterBackoff implements "decorrelated jitter" from the AWS
+// Architecture Blog: sleep = min(max, random_between(base, prev*3)).
+// The previous sleep is per-strategy state, so it is guarded by a mutex.
+func decorrelatedJitterBackoff(base, maxDelay time.Duration) httpx.BackoffStrategy {
+	var (
+		mu   sync.Mutex
+		prev = base
+	)
+	return func(attempt int) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		if attempt == 0 {
+			prev = base
+		}
+		prev = min(base+rand.N(prev*3-base+1), maxDelay)
+		return prev
+	}
+}
+
+// giveUpKey carries a call's attempt counter for giveUpMiddleware.
+type giveUpKey struct{}
+
+// withGiveUp returns ctx with an attempt counter for one call; pass it to
+// the request and install giveUpMiddleware.
+func withGiveUp(ctx context.Context) context.Context {
+	return context.WithValue(ctx, giveUpKey{}, new(atomic.Int32))
+}
+
+// giveUpMiddleware calls fn once per call when retries ran out: the attempt
+// numbered policy.MaxAttempts, after which the retry loop stops whatever the
+// outcome, returned an error or a 4xx/5xx status. Retries run the chain
+// again, so it counts attempts in the counter from withGiveUp; calls
+// without one are ignored. policy is only read, never changed.
+func giveUpMiddleware(policy *httpx.RetryPolicy, fn func(req *http.Request, resp *http.Response, err error)) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			n, ok := req.Context().Value(giveUpKey{}).(*atomic.Int32)
+			if !ok {
+				return next.RoundTrip(req)
+			}
+			attempt := int(n.Add(1))
+			resp, err := next.RoundTrip(req)
+			if attempt == max(policy.MaxAttempts, 1) && (err != nil || resp.StatusCode >= 400) {
+				fn(req, resp, err)
+			}
+			return resp, err
+		})
+	}
+}
+
+// retryCountKey carries a call's attempt counter in its context.
+type retryCountKey struct{}
+
+// withRetryCount returns ctx with an attempt counter for one call; pass it
+// to the request and install retryCountMiddleware.
+func withRetryCount(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryCountKey{}, new(atomic.Int32))
+}
+
+// retryCountMiddleware counts attempts in the counter from withRetryCount.
+// Retries run the transport chain again, so each attempt passes through it
+// once; middleware it wraps sees the updated count.
+func retryCountMiddleware() httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if n, ok := req.Context().Value(retryCountKey{}).(*atomic.Int32); ok {
+				n.Add(1)
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// retryCount returns the zero-based index of the attempt in progress: 0 for
+// the first try, 1 for the first retry. It is 0 without withRetryCount.
+func retryCount(ctx context.Context) int {
+	n, ok := ctx.Value(retryCountKey{}).(*atomic.Int32)
+	if !ok {
+		return 0
+	}
+	return max(int(n.Load())-1, 0)
+}
+
+// isRetry reports whether the attempt in progress is a retry.
+func isRetry(ctx context.Context) bool {
+	return retryCount(ctx) > 0
+}
+
+// errBodyNotRewindable stops an attempt whose body can't be sent again.
+var errBodyNotRewindable = errors.New("retry: request body cannot be rewound")
+
+// rewindBodyMiddleware calls rewind on a clone of the request before every
+// attempt and sends the clone, leaving the caller's request alone. httpx's
+// retry already replays the body between attempts; this is for a transport
+// set with WithTransport that resends by itself (auth refresh, 307/308) and
+// needs GetBody, which a plain io.Reader body does not have. An error from
+// rewind fails the attempt with that error.
+func rewindBodyMiddleware(rewind func(*http.Request) error) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			out := req.Clone(req.Context())
+			if err := rewind(out); err != nil {
+				return nil, err
+			}
+			return next.RoundTrip(out)
+		})
+	}
+}
+
+// rewindBody is the default rewind function. It reopens the body with
+// GetBody when there is one, seeks an io.Seeker back to the start, and
+// otherwise buffers the body and sets GetBody for later sends.
+func rewindBody(req *http.Request) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return fmt.Errorf("%w: %v", errBodyNotRewindable, err)
+		}
+		req.Body = body
+		return nil
+	}
+	if seeker, ok := req.Body.(io.Seeker); ok {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("%w: %v", errBodyNotRewindable, err)
+		}
+		return nil
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("%w: %v", errBodyNotRewindable, err)
+	}
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data)), nil
+	}
+	req.Body, _ = req.GetBody()
+	return nil
+}
+
+// capBackoff clamps every delay produced by inner to maxDelay.
+func capBackoff(inner httpx.BackoffStrategy, maxDelay time.Duration) httpx.BackoffStrategy {
+	return func(attempt int) time.Duration {
+		return min(inner(attempt), maxDelay)
+	}
+}
+
+// clampToDeadline caps every delay produced by inner to the time left
+// before deadline.
+func clampToDeadline(deadline time.Time, inner httpx.BackoffStrategy) httpx.BackoffStrategy {
+	return func(attempt int) time.Duration {
+		return max(min(inner(attempt), time.Until(deadline)), 0)
+	}
+}
+
+// retryAfterOnRetry returns a RetryPolicy.OnRetry that waits for the
+// Retry-After hint of the response being retried, or fallback(attempt-1)
+// when there is none, until req's context is done. BackoffStrategy only sees
+// the attempt number and cannot tell concurrent calls apart, so the wait
+// happens here, per call; pair it with a zero Backoff. onRetry, if not nil,
+// is called first.
+func retryAfterOnRetry(fallback httpx.BackoffStrategy, onRetry func(attempt int, req *http.Request, resp *http.Response, err error)) func(attempt int, req *http.Request, resp *http.Response, err error) {
+	return func(attempt int, req *http.Request, resp *http.Response, err error) {
+		if onRetry != nil {
+			onRetry(attempt, req, resp, err)
+		}
+		wait, ok := time.Duration(0), false
+		if resp != nil {
+			wait, ok = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+		if !ok {
+			wait = fallback(attempt - 1)
+		}
+		if wait <= 0 {
+			return
+		}
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-req.Context().Done():
+		case <-t.C:
+		}
+	}
+}
+
+// parseRetryAfter accepts both forms allowed by RFC 9110: delay-seconds
+// ("120") and an HTTP-date ("Wed, 21 Oct 2015 07:28:00 GMT").
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -2,18 +2,27 @@
 // - SingleflightMiddleware for concurrent GET deduplication
 // - WithSingleflight client-level option
 // - Only GET is deduplicated (POST is not)
+// - Custom deduplication keys
+// - Forgetting an in-flight key to force a fresh fetch
+// - Deduplicating HEAD and other idempotent methods
+// - Reusing a completed result for a short TTL
 package singleflight
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/n0l3r/httpx"
+	"golang.org/x/sync/singleflight"
 )
 
 // Run executes all singleflight examples.
@@ -26,6 +35,10 @@ func Run() {
 	exampleWithSingleflight()
 	examplePostNotDeduplicated()
 	exampleSingleflightLatency()
+	exampleSingleflightKey()
+	exampleSingleflightForget()
+	exampleSingleflightMethods()
+	exampleSingleflightTTL()
 }
 
 // [1] SingleflightMiddleware — concurrent GET deduplication.
@@ -174,3 +187,327 @@ func exampleSingleflightLatency() {
 		time.Duration(numConcurrent)*serverDelay)
 	fmt.Printf("    With singleflight: ~%v (single in-flight)\n", serverDelay)
 }
+
+// [5] Custom key — requests differing only in a timestamp param share a flight.
+func exampleSingleflightKey() {
+	fmt.Println("\n[5] withSingleflightKey — ignore a volatile query parameter")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprintf(w, `{"report":%q}`, r.URL.Query().Get("id"))
+	}))
+	defer srv.Close()
+
+	// Drop ?timestamp= from the key; everything else still distinguishes requests.
+	ignoreTimestamp := func(req *http.Request) string {
+		u := *req.URL
+		q := u.Query()
+		q.Del("timestamp")
+		u.RawQuery = q.Encode()
+		return req.Method + " " + u.String()
+	}
+	sf := newSingleflight(withSingleflightKey(ignoreTimestamp))
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(sf.Middleware()))
+
+	var wg sync.WaitGroup
+	for i := range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q := url.Values{"id": {"q3"}, "timestamp": {fmt.Sprint(time.Now().UnixNano() + int64(i))}}
+			c.Get(context.Background(), "/report?"+q.Encode())
+		}()
+	}
+	wg.Wait()
+	fmt.Printf("  ✓ 6 requests with distinct timestamps → server called %d time(s)\n", calls.Load())
+
+	c.Get(context.Background(), "/report?id=q4&timestamp=1")
+	fmt.Printf("    different id still goes through: %d call(s) total\n", calls.Load())
+}
+
+// [6] Forget — the next caller starts a new request while the old one runs.
+func exampleSingleflightForget() {
+	fmt.Println("\n[6] Forget — force a fresh fetch mid-flight")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		time.Sleep(150 * time.Millisecond)
+		fmt.Fprintf(w, `{"version":%d}`, n)
+	}))
+	defer srv.Close()
+
+	sf := newSingleflight()
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(sf.Middleware()))
+
+	results := make([]string, 3)
+	var wg sync.WaitGroup
+	get := func(i int) {
+		defer wg.Done()
+		if resp, err := c.Get(context.Background(), "/config"); err == nil {
+			results[i] = resp.String()
+		}
+	}
+
+	wg.Add(2)
+	go get(0)
+	go get(1) // joins the first flight
+	time.Sleep(50 * time.Millisecond)
+
+	sf.Forget("GET " + srv.URL + "/config") // config changed; don't reuse the old flight
+	wg.Add(1)
+	go get(2)
+	wg.Wait()
+
+	fmt.Printf("  ✓ server called %d time(s)\n", calls.Load())
+	for i, r := range results {
+		fmt.Printf("    caller %d: %s\n", i+1, r)
+	}
+}
+
+// [7] Methods — HEAD deduplicated alongside GET; POST always forwarded.
+func exampleSingleflightMethods() {
+	fmt.Println("\n[7] withSingleflightMethods — GET and HEAD")
+
+	var heads, posts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodHead:
+			heads.Add(1)
+		case http.MethodPost:
+			posts.Add(1)
+		}
+		time.Sleep(50 * time.Millisecond)
+		w.Header().Set("Content-Length", "1024")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	sf := newSingleflight(withSingleflightMethods(http.MethodGet, http.MethodHead))
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(sf.Middleware()))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			c.Execute(context.Background(), http.MethodHead, "/artifacts/build.tar.gz")
+		}()
+		go func() {
+			defer wg.Done()
+			c.Post(context.Background(), "/artifacts", httpx.WithJSONBody(map[string]string{"name": "build"}))
+		}()
+	}
+	wg.Wait()
+
+	fmt.Printf("  ✓ 5 concurrent HEAD → server saw %d\n", heads.Load())
+	fmt.Printf("  ✓ 5 concurrent POST → server saw %d\n", posts.Load())
+}
+
+// [8] TTL — a burst arriving just after a flight lands reuses its result.
+func exampleSingleflightTTL() {
+	fmt.Println("\n[8] withSingleflightTTL — bridge singleflight and short-TTL caching")
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Path == "/flaky" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		fmt.Fprintf(w, `{"version":%d}`, n)
+	}))
+	defer srv.Close()
+
+	sf := newSingleflight(withSingleflightTTL(50 * time.Millisecond))
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(sf.Middleware()))
+
+	get := func(label, path string) {
+		resp, err := c.Get(context.Background(), path)
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			return
+		}
+		fmt.Printf("  ✓ %-28s %s (server calls: %d)\n", label, resp.String(), calls.Load())
+	}
+
+	get("first request:", "/config")
+	time.Sleep(10 * time.Millisecond)
+	get("10ms after completion:", "/config")
+	time.Sleep(60 * time.Millisecond)
+	get("after the 50ms TTL:", "/config")
+
+	get("503 from /flaky:", "/flaky")
+	time.Sleep(10 * time.Millisecond)
+	get("10ms later (5xx not kept):", "/flaky")
+}
+
+// ---
+
+// singleflightGroup deduplicates concurrent requests (GET by default) with
+// the same key: one request reaches the server and every caller gets its own
+// copy of the response. The shared request runs with the first caller's
+// context.
+type singleflightGroup struct {
+	group   singleflight.Group
+	key     func(*http.Request) string
+	methods []string
+	ttl     time.Duration
+
+	mu     sync.Mutex
+	recent map[string]recentResponse // completed flights kept for ttl
+	gens   map[string]uint64         // bumped by Forget; stale flights aren't kept
+}
+
+// recentResponse is a completed flight reused until expires.
+type recentResponse struct {
+	shared  sharedResponse
+	expires time.Time
+}
+
+// singleflightOption configures newSingleflight.
+type singleflightOption func(*singleflightGroup)
+
+// withSingleflightKey sets the deduplication key; the default is the method
+// and full URL.
+func withSingleflightKey(fn func(*http.Request) string) singleflightOption {
+	return func(g *singleflightGroup) { g.key = fn }
+}
+
+// withSingleflightMethods sets which methods are deduplicated; the default
+// is GET. Only list idempotent methods without request bodies.
+func withSingleflightMethods(methods ...string) singleflightOption {
+	return func(g *singleflightGroup) { g.methods = methods }
+}
+
+// withSingleflightTTL keeps each 2xx result for ttl after it completes, so
+// callers arriving shortly after a flight lands reuse it instead of starting
+// another. The default, 0, only joins in-flight requests.
+func withSingleflightTTL(ttl time.Duration) singleflightOption {
+	return func(g *singleflightGroup) { g.ttl = ttl }
+}
+
+func newSingleflight(opts ...singleflightOption) *singleflightGroup {
+	g := &singleflightGroup{
+		key:     func(req *http.Request) string { return req.Method + " " + req.URL.String() },
+		methods: []string{http.MethodGet},
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Forget drops key from the group: callers already waiting still get the
+// in-flight response, but the next caller starts a new request. A result
+// kept by withSingleflightTTL is dropped too, and a flight started before
+// Forget is not kept when it lands.
+func (g *singleflightGroup) Forget(key string) {
+	g.mu.Lock()
+	g.group.Forget(key)
+	delete(g.recent, key)
+	if g.gens == nil {
+		g.gens = map[string]uint64{}
+	}
+	g.gens[key]++
+	g.mu.Unlock()
+}
+
+// generation returns the Forget count for key; a flight records it when it
+// starts and remember compares it when the flight lands.
+func (g *singleflightGroup) generation(key string) uint64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.gens[key]
+}
+
+// lookup returns the unexpired completed result for key, if any.
+func (g *singleflightGroup) lookup(key string) (sharedResponse, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	r, ok := g.recent[key]
+	if !ok || time.Now().After(r.expires) {
+		return sharedResponse{}, false
+	}
+	return r.shared, true
+}
+
+// remember keeps shared under key for the TTL and schedules its removal.
+// Nothing is kept if key was forgotten since the flight's generation gen.
+func (g *singleflightGroup) remember(key string, gen uint64, shared sharedResponse) {
+	expires := time.Now().Add(g.ttl)
+	g.mu.Lock()
+	if g.gens[key] != gen {
+		g.mu.Unlock()
+		return
+	}
+	if g.recent == nil {
+		g.recent = map[string]recentResponse{}
+	}
+	g.recent[key] = recentResponse{shared: shared, expires: expires}
+	g.mu.Unlock()
+
+	time.AfterFunc(g.ttl, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if r, ok := g.recent[key]; ok && r.expires.Equal(expires) {
+			delete(g.recent, key)
+		}
+	})
+}
+
+// sharedResponse is the buffered result handed to every waiting caller.
+type sharedResponse struct {
+	resp *http.Response
+	body []byte
+}
+
+// copyFor returns a response of its own for req: cloned headers and a
+// fresh reader over the shared body.
+func (s sharedResponse) copyFor(req *http.Request) *http.Response {
+	out := *s.resp
+	out.Header = s.resp.Header.Clone()
+	out.Body = io.NopCloser(bytes.NewReader(s.body))
+	out.Request = req
+	return &out
+}
+
+// Middleware returns the httpx middleware for this group.
+func (g *singleflightGroup) Middleware() httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if !slices.Contains(g.methods, req.Method) {
+				return next.RoundTrip(req)
+			}
+			key := g.key(req)
+			if g.ttl > 0 {
+				if shared, ok := g.lookup(key); ok {
+					return shared.copyFor(req), nil
+				}
+			}
+			v, err, _ := g.group.Do(key, func() (any, error) {
+				gen := g.generation(key)
+				resp, err := next.RoundTrip(req)
+				if err != nil {
+					return nil, err
+				}
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				if err != nil {
+					return nil, err
+				}
+				shared := sharedResponse{resp: resp, body: body}
+				if g.ttl > 0 && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+					g.remember(key, gen, shared)
+				}
+				return shared, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			return v.(sharedResponse).copyFor(req), nil
+		})
+	}
+}
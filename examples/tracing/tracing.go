@@ -3,6 +3,13 @@
 // - Trace context propagation via W3C headers
 // - Span attributes (method, URL, status)
 // - Error recording
+// - Custom span names
+// - Scrubbing sensitive values from span attributes
+// - B3 (Zipkin) propagation, single- and multi-header
+// - Span events for retry attempts
+// - Tracing as an httpx middleware instead of a transport
+// - OpenTelemetry client metrics (duration, total, in-flight)
+// - Baggage propagation (tenant, user, feature flags)
 package tracing
 
 import (
@@ -10,11 +17,21 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
 	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
@@ -33,6 +50,13 @@ func Run() {
 	exampleTracePropagation()
 	exampleErrorSpan()
 	exampleManualSpan()
+	exampleSpanNameFunc()
+	exampleAttributeFilter()
+	exampleB3Propagation()
+	exampleRetrySpanEvents()
+	exampleTracingMiddleware()
+	exampleMetricsMiddleware()
+	exampleBaggage()
 }
 
 // setupTracer creates an in-memory span exporter and returns a tracer + exporter.
@@ -174,3 +198,569 @@ func exampleManualSpan() {
 		fmt.Printf("    [%s] %s\n", s.Status().Code, s.Name())
 	}
 }
+
+// [5] Custom span names — method, host and path instead of "HTTP GET".
+func exampleSpanNameFunc() {
+	fmt.Println("\n[5] Custom span names — SpanNameFunc")
+
+	tracer, recorder := setupTracer()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	transport := &tracingTransport{Tracer: tracer, SpanNameFunc: methodHostPathSpanName}
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(transport))
+
+	for _, path := range []string{"/users/42", "/orders"} {
+		c.Get(context.Background(), path)
+	}
+	c.Post(context.Background(), "/orders", httpx.WithJSONBody(map[string]int{"qty": 1}))
+
+	for _, s := range recorder.Ended() {
+		fmt.Printf("  ✓ span %q\n", s.Name())
+	}
+	fmt.Printf("    default name: %q\n", defaultSpanName(&http.Request{Method: http.MethodGet}))
+}
+
+// [6] Attribute filter — sensitive query parameters never reach the exporter.
+func exampleAttributeFilter() {
+	fmt.Println("\n[6] Attribute filter — RedactQueryParams")
+
+	tracer, recorder := setupTracer()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	transport := &tracingTransport{
+		Tracer:          tracer,
+		AttributeFilter: redactQueryParams("access_token", "email"),
+	}
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(transport))
+
+	req, _ := c.NewRequest(context.Background(), http.MethodGet, "/search").
+		Query("q", "shoes").
+		Query("email", "alice@example.com").
+		Query("access_token", "eyJhbGciOi...").
+		Build()
+	c.Do(req)
+
+	for _, s := range recorder.Ended() {
+		for _, kv := range s.Attributes() {
+			if kv.Key == "url.full" {
+				fmt.Printf("  ✓ url.full = %s\n", kv.Value.AsString())
+			}
+		}
+	}
+}
+
+// [7] B3 propagation — Zipkin-style headers instead of traceparent.
+func exampleB3Propagation() {
+	fmt.Println("\n[7] B3 propagation — multi-header and single-header")
+
+	tracer, _ := setupTracer()
+
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	ctx, span := tracer.Start(context.Background(), "b3-operation")
+	defer span.End()
+	fmt.Printf("    parent trace id: %s\n", span.SpanContext().TraceID())
+
+	for _, prop := range []b3Propagator{{}, {SingleHeader: true}} {
+		transport := &tracingTransport{Tracer: tracer, Propagator: prop}
+		c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(transport))
+		c.Get(ctx, "/zipkin")
+
+		if prop.SingleHeader {
+			fmt.Printf("  ✓ b3: %s\n", got.Get("b3"))
+		} else {
+			fmt.Printf("  ✓ X-B3-TraceId: %s\n", got.Get("X-B3-TraceId"))
+			fmt.Printf("    X-B3-SpanId:  %s\n", got.Get("X-B3-SpanId"))
+			fmt.Printf("    X-B3-Sampled: %s\n", got.Get("X-B3-Sampled"))
+		}
+
+		// The server side reads it back with the same propagator.
+		remote := trace.SpanContextFromContext(prop.Extract(context.Background(), propagation.HeaderCarrier(got)))
+		fmt.Printf("    extracted trace id matches: %v\n", remote.TraceID() == span.SpanContext().TraceID())
+		fmt.Printf("    traceparent sent: %v\n", got.Get("Traceparent") != "")
+	}
+}
+
+// [8] Retry span events — one "http.retry" event per retry on the caller's span.
+func exampleRetrySpanEvents() {
+	fmt.Println("\n[8] Retry attempts recorded as span events")
+
+	tracer, recorder := setupTracer()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	policy := withRetrySpanEvents(&httpx.RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     httpx.ConstantBackoff(0),
+		Conditions:  []httpx.RetryConditionFunc{httpx.RetryOnStatus5xx},
+	})
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithRetryPolicy(policy))
+
+	ctx, span := tracer.Start(context.Background(), "charge-card")
+	resp, err := c.Get(ctx, "/charge")
+	span.End()
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	fmt.Printf("  ✓ final status=%d after %d calls\n", resp.StatusCode(), calls.Load())
+
+	for _, s := range recorder.Ended() {
+		for _, ev := range s.Events() {
+			fmt.Printf("    %s event %q", s.Name(), ev.Name)
+			for _, kv := range ev.Attributes {
+				fmt.Printf(" %s=%s", kv.Key, kv.Value.Emit())
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// [9] Tracing middleware — same spans as the transport, via WithMiddleware.
+func exampleTracingMiddleware() {
+	fmt.Println("\n[9] Tracing middleware vs transport — equivalent spans")
+
+	tracer, recorder := setupTracer()
+
+	var traceparents []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("Traceparent"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	redact := redactQueryParams("token")
+	viaTransport, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(&tracingTransport{
+		Tracer:          tracer,
+		Propagator:      propagation.TraceContext{},
+		SpanNameFunc:    methodHostPathSpanName,
+		AttributeFilter: redact,
+	}))
+	viaMiddleware, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(newTracingMiddleware(tracer,
+		withPropagator(propagation.TraceContext{}),
+		withSpanNameFunc(methodHostPathSpanName),
+		withAttributeFilter(redact),
+	)))
+
+	ctx, parent := tracer.Start(context.Background(), "compare")
+	viaTransport.Get(ctx, "/users?token=abc")
+	viaMiddleware.Get(ctx, "/users?token=abc")
+	parent.End()
+
+	for _, s := range recorder.Ended() {
+		if s.Name() == "compare" {
+			continue
+		}
+		fmt.Printf("  ✓ %q kind=%s parent=%v attrs=%d\n",
+			s.Name(), s.SpanKind(), s.Parent().SpanID() == parent.SpanContext().SpanID(), len(s.Attributes()))
+	}
+	fmt.Printf("    traceparent sent by both: %v\n", len(traceparents) == 2 && traceparents[0] != "" && traceparents[1] != "")
+}
+
+// [10] Metrics middleware — request duration, count and in-flight gauges.
+func exampleMetricsMiddleware() {
+	fmt.Println("\n[10] OTel metrics middleware — duration, total, inflight")
+
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer provider.Shutdown(context.Background())
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	mw, err := newMetricsMiddleware(provider.Meter("httpx-demo"), withDurationBoundaries(.001, .01, .1, 1))
+	if err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithMiddleware(mw))
+	for _, path := range []string{"/ok", "/ok", "/fail"} {
+		c.Get(context.Background(), path)
+	}
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		fmt.Printf("  ✗ %v\n", err)
+		return
+	}
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Sum[int64]:
+				for _, dp := range data.DataPoints {
+					class, _ := dp.Attributes.Value("http.response.status_class")
+					fmt.Printf("  ✓ %s{%s} = %d\n", m.Name, class.Emit(), dp.Value)
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					class, _ := dp.Attributes.Value("http.response.status_class")
+					fmt.Printf("  ✓ %s{%s} count=%d sum=%.3fs\n", m.Name, class.Emit(), dp.Count, dp.Sum)
+				}
+			}
+		}
+	}
+}
+
+// [11] Baggage — ambient key-values ride along with the trace.
+func exampleBaggage() {
+	fmt.Println("\n[11] Baggage propagation — tenant and user to downstream services")
+
+	tracer, _ := setupTracer()
+
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	tenant, _ := baggage.NewMember("tenant.id", "acme")
+	user, _ := baggage.NewMember("user.id", "42")
+	bag, _ := baggage.New(tenant, user)
+	ctx := baggage.ContextWithBaggage(context.Background(), bag)
+
+	withBaggage, _ := httpx.New(httpx.WithBaseURL(srv.URL),
+		httpx.WithTransport(&tracingTransport{Tracer: tracer, Propagator: propagation.TraceContext{}}))
+	withBaggage.Get(ctx, "/invoices")
+	fmt.Printf("  ✓ baggage: %s\n", got.Get("Baggage"))
+	remote := baggage.FromContext(propagation.Baggage{}.Extract(context.Background(), propagation.HeaderCarrier(got)))
+	fmt.Printf("    server reads tenant.id=%s user.id=%s\n", remote.Member("tenant.id").Value(), remote.Member("user.id").Value())
+
+	// Calls to third parties shouldn't leak tenant or user IDs.
+	external, _ := httpx.New(httpx.WithBaseURL(srv.URL),
+		httpx.WithMiddleware(newTracingMiddleware(tracer, withoutBaggage())))
+	external.Get(ctx, "/partner-api")
+	fmt.Printf("  ✓ withoutBaggage: baggage header sent: %v, traceparent sent: %v\n",
+		got.Get("Baggage") != "", got.Get("Traceparent") != "")
+}
+
+// ---
+
+// tracingTransport traces requests like httpxtracing.Transport — one
+// client span per request, context propagation, method/URL/status
+// attributes and error status on 5xx — with hooks for naming spans.
+type tracingTransport struct {
+	Tracer       trace.Tracer
+	Propagator   propagation.TextMapPropagator // nil means otel.GetTextMapPropagator()
+	Base         http.RoundTripper             // nil means http.DefaultTransport
+	SpanNameFunc func(*http.Request) string    // nil means defaultSpanName
+
+	// AttributeFilter, if set, sees every string attribute before it is
+	// recorded and returns the value to keep (e.g. redacted or hashed).
+	AttributeFilter func(key, value string) string
+
+	// SkipBaggage stops the context's baggage being sent in the baggage header.
+	SkipBaggage bool
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	prop := t.Propagator
+	if prop == nil {
+		prop = otel.GetTextMapPropagator()
+	}
+	name := defaultSpanName
+	if t.SpanNameFunc != nil {
+		name = t.SpanNameFunc
+	}
+
+	ctx, span := t.Tracer.Start(req.Context(), name(req), trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes(
+		t.attr("http.request.method", req.Method),
+		t.attr("url.full", req.URL.String()),
+		t.attr("server.address", req.URL.Host),
+	)
+
+	out := req.Clone(ctx)
+	prop.Inject(ctx, propagation.HeaderCarrier(out.Header))
+	if !t.SkipBaggage {
+		propagation.Baggage{}.Inject(ctx, propagation.HeaderCarrier(out.Header))
+	}
+
+	resp, err := base.RoundTrip(out)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.SetStatus(codes.Error, strconv.Itoa(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// tracingOption configures the tracingTransport built by newTracingMiddleware.
+type tracingOption func(*tracingTransport)
+
+func withSpanNameFunc(fn func(*http.Request) string) tracingOption {
+	return func(t *tracingTransport) { t.SpanNameFunc = fn }
+}
+
+func withAttributeFilter(fn func(key, value string) string) tracingOption {
+	return func(t *tracingTransport) { t.AttributeFilter = fn }
+}
+
+func withPropagator(p propagation.TextMapPropagator) tracingOption {
+	return func(t *tracingTransport) { t.Propagator = p }
+}
+
+// withoutBaggage stops the middleware from sending the baggage header.
+func withoutBaggage() tracingOption {
+	return func(t *tracingTransport) { t.SkipBaggage = true }
+}
+
+// newTracingMiddleware is tracingTransport as an httpx.Middleware, so
+// tracing takes its place in the WithMiddleware chain rather than sitting
+// under it as the base transport. Spans are identical either way.
+func newTracingMiddleware(tracer trace.Tracer, opts ...tracingOption) httpx.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		t := &tracingTransport{Tracer: tracer, Base: next}
+		for _, opt := range opts {
+			opt(t)
+		}
+		return t
+	}
+}
+
+func (t *tracingTransport) attr(key, value string) attribute.KeyValue {
+	if t.AttributeFilter != nil {
+		value = t.AttributeFilter(key, value)
+	}
+	return attribute.String(key, value)
+}
+
+// redactQueryParams returns an AttributeFilter that replaces the values of
+// the named query parameters with [REDACTED] in any URL-valued attribute,
+// leaving the rest of the URL untouched.
+func redactQueryParams(params ...string) func(key, value string) string {
+	return func(_, value string) string {
+		u, err := url.Parse(value)
+		if err != nil || u.RawQuery == "" {
+			return value
+		}
+		pairs := strings.Split(u.RawQuery, "&")
+		for i, pair := range pairs {
+			name, _, _ := strings.Cut(pair, "=")
+			if unescaped, err := url.QueryUnescape(name); err == nil && slices.Contains(params, unescaped) {
+				pairs[i] = name + "=[REDACTED]"
+			}
+		}
+		u.RawQuery = strings.Join(pairs, "&")
+		return u.String()
+	}
+}
+
+// defaultSpanName names a span "HTTP {METHOD}".
+func defaultSpanName(req *http.Request) string {
+	return "HTTP " + req.Method
+}
+
+// methodHostPathSpanName names a span "HTTP {METHOD} {host}{path}". Paths
+// with IDs in them make for high-cardinality names; prefer route templates
+// where the caller knows them.
+func methodHostPathSpanName(req *http.Request) string {
+	return "HTTP " + req.Method + " " + req.URL.Host + req.URL.Path
+}
+
+// b3Propagator is a propagation.TextMapPropagator for Zipkin B3 headers.
+// Extract accepts both the single "b3" header and the X-B3-* headers;
+// Inject writes X-B3-* unless SingleHeader is set.
+type b3Propagator struct {
+	SingleHeader bool
+}
+
+const (
+	b3Single       = "b3"
+	b3TraceID      = "X-B3-TraceId"
+	b3SpanID       = "X-B3-SpanId"
+	b3ParentSpanID = "X-B3-ParentSpanId"
+	b3Sampled      = "X-B3-Sampled"
+	b3Flags        = "X-B3-Flags"
+)
+
+func (p b3Propagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	sampled := "0"
+	if sc.IsSampled() {
+		sampled = "1"
+	}
+	if p.SingleHeader {
+		carrier.Set(b3Single, sc.TraceID().String()+"-"+sc.SpanID().String()+"-"+sampled)
+		return
+	}
+	carrier.Set(b3TraceID, sc.TraceID().String())
+	carrier.Set(b3SpanID, sc.SpanID().String())
+	carrier.Set(b3Sampled, sampled)
+}
+
+func (p b3Propagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	var traceID, spanID, sampled string
+	if single := carrier.Get(b3Single); single != "" {
+		// {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId}; the last two are optional.
+		parts := strings.Split(single, "-")
+		if len(parts) < 2 {
+			return ctx
+		}
+		traceID, spanID = parts[0], parts[1]
+		if len(parts) > 2 {
+			sampled = parts[2]
+		}
+	} else {
+		traceID, spanID, sampled = carrier.Get(b3TraceID), carrier.Get(b3SpanID), carrier.Get(b3Sampled)
+		if carrier.Get(b3Flags) == "1" {
+			sampled = "d"
+		}
+	}
+
+	if len(traceID) == 16 { // 64-bit trace IDs are left-padded to 128 bits
+		traceID = strings.Repeat("0", 16) + traceID
+	}
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		return ctx
+	}
+	sid, err := trace.SpanIDFromHex(spanID)
+	if err != nil {
+		return ctx
+	}
+	var flags trace.TraceFlags
+	if sampled == "1" || sampled == "d" || sampled == "true" {
+		flags = trace.FlagsSampled
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: flags,
+		Remote:     true,
+	}))
+}
+
+func (p b3Propagator) Fields() []string {
+	return []string{b3Single, b3TraceID, b3SpanID, b3ParentSpanID, b3Sampled, b3Flags}
+}
+
+// withRetrySpanEvents makes policy add an "http.retry" event to the span in
+// the request's context on every retry, then calls the existing OnRetry.
+// Retries stay events on one span rather than sibling spans.
+func withRetrySpanEvents(policy *httpx.RetryPolicy) *httpx.RetryPolicy {
+	next := policy.OnRetry
+	policy.OnRetry = func(attempt int, req *http.Request, resp *http.Response, err error) {
+		if span := trace.SpanFromContext(req.Context()); span.IsRecording() {
+			attrs := []attribute.KeyValue{attribute.Int("http.retry.attempt", attempt)}
+			if resp != nil {
+				attrs = append(attrs, attribute.Int("http.response.status_code", resp.StatusCode))
+			}
+			if err != nil {
+				attrs = append(attrs, attribute.String("error.message", err.Error()))
+			}
+			span.AddEvent("http.retry", trace.WithAttributes(attrs...))
+		}
+		if next != nil {
+			next(attempt, req, resp, err)
+		}
+	}
+	return policy
+}
+
+// metricsConfig holds newMetricsMiddleware options.
+type metricsConfig struct {
+	durationBoundaries []float64
+}
+
+// metricsOption configures newMetricsMiddleware.
+type metricsOption func(*metricsConfig)
+
+// withDurationBoundaries sets the histogram bucket boundaries, in seconds.
+func withDurationBoundaries(bounds ...float64) metricsOption {
+	return func(c *metricsConfig) { c.durationBoundaries = bounds }
+}
+
+// newMetricsMiddleware records OpenTelemetry client metrics for every
+// request: http.client.request.duration (histogram, seconds),
+// http.client.request.total (counter) and http.client.request.inflight
+// (up-down counter). Attributes are method, host and a status class such
+// as "2xx" ("error" when no response arrived); the in-flight gauge has no
+// status.
+func newMetricsMiddleware(meter metric.Meter, opts ...metricsOption) (httpx.Middleware, error) {
+	cfg := metricsConfig{durationBoundaries: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	duration, err := meter.Float64Histogram("http.client.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP client requests."),
+		metric.WithExplicitBucketBoundaries(cfg.durationBoundaries...))
+	if err != nil {
+		return nil, err
+	}
+	total, err := meter.Int64Counter("http.client.request.total",
+		metric.WithDescription("Number of HTTP client requests."))
+	if err != nil {
+		return nil, err
+	}
+	inflight, err := meter.Int64UpDownCounter("http.client.request.inflight",
+		metric.WithDescription("Number of HTTP client requests in flight."))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			ctx := req.Context()
+			base := []attribute.KeyValue{
+				attribute.String("http.request.method", req.Method),
+				attribute.String("server.address", req.URL.Host),
+			}
+			inflight.Add(ctx, 1, metric.WithAttributes(base...))
+			defer inflight.Add(ctx, -1, metric.WithAttributes(base...))
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			class := "error"
+			if err == nil {
+				class = fmt.Sprintf("%dxx", resp.StatusCode/100)
+			}
+			attrs := metric.WithAttributes(append(base, attribute.String("http.response.status_class", class))...)
+			duration.Record(ctx, time.Since(start).Seconds(), attrs)
+			total.Add(ctx, 1, attrs)
+			return resp, err
+		})
+	}, nil
+}
@@ -0,0 +1,351 @@
+// Package transport demonstrates tuning the http.Transport under an httpx client:
+// - Preconnecting to upstream hosts at startup
+// - Per-host connection pool metrics (active, idle, total)
+// - Fine-grained http.Transport settings (compression, buffers, HTTP/2)
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/n0l3r/httpx"
+)
+
+// Run executes all transport examples.
+func Run() {
+	fmt.Println("\n═══════════════════════════════════════════")
+	fmt.Println("  TRANSPORT EXAMPLES")
+	fmt.Println("═══════════════════════════════════════════")
+
+	examplePreconnect()
+	examplePoolMetrics()
+	exampleTransportConfig()
+}
+
+// [1] Preconnect — dial upstreams in the background so the first request
+// doesn't pay for connection setup.
+func examplePreconnect() {
+	fmt.Println("\n[1] Preconnect — warm connections at client creation")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	// Simulate a far-away upstream: every TCP dial takes 80ms.
+	slowDial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		time.Sleep(80 * time.Millisecond)
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+
+	firstRequest := func(warm bool) time.Duration {
+		tr := &http.Transport{DialContext: slowDial}
+		defer tr.CloseIdleConnections()
+		if warm {
+			wait := preconnect(tr, srv.URL)
+			wait() // a real service would carry on starting up instead
+		}
+		c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(tr))
+		start := time.Now()
+		c.Get(context.Background(), "/")
+		return time.Since(start)
+	}
+
+	cold, warm := firstRequest(false), firstRequest(true)
+	fmt.Printf("  ✓ first request without preconnect: %v\n", cold.Round(time.Millisecond))
+	fmt.Printf("  ✓ first request with preconnect:    %v\n", warm.Round(time.Millisecond))
+}
+
+// [2] Pool metrics — active/idle/total connections per host.
+func examplePoolMetrics() {
+	fmt.Println("\n[2] Connection pool metrics per host")
+
+	release := make(chan struct{})
+	var inHandler atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inHandler.Add(1)
+		<-release
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	metrics := newPoolMetrics(nil)
+	tr := &http.Transport{MaxIdleConnsPerHost: 2}
+	defer tr.CloseIdleConnections()
+	c, _ := httpx.New(httpx.WithBaseURL(srv.URL), httpx.WithTransport(metrics.Instrument(tr)))
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Get(context.Background(), "/work")
+		}()
+	}
+	for inHandler.Load() < 5 {
+		time.Sleep(time.Millisecond)
+	}
+	for host, s := range metrics.Stats() {
+		fmt.Printf("  ✓ saturated  %s active=%d idle=%d total=%d\n", host, s.Active, s.Idle, s.Total)
+	}
+
+	close(release)
+	wg.Wait()
+	time.Sleep(10 * time.Millisecond) // let the transport park or close connections
+	for host, s := range metrics.Stats() {
+		fmt.Printf("  ✓ drained    %s active=%d idle=%d total=%d (MaxIdleConnsPerHost=2)\n", host, s.Active, s.Idle, s.Total)
+	}
+}
+
+// [3] Transport config — the knobs WithConnectionPool doesn't cover.
+func exampleTransportConfig() {
+	fmt.Println("\n[3] withTransportConfig — tune the underlying *http.Transport")
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%q", r.Header.Get("Accept-Encoding"))
+	}))
+	defer srv.Close()
+
+	for _, disable := range []bool{false, true} {
+		opt, err := withTransportConfig(nil, func(tr *http.Transport) {
+			tr.DisableCompression = disable
+			tr.MaxResponseHeaderBytes = 64 << 10
+			tr.ReadBufferSize = 32 << 10
+			tr.WriteBufferSize = 32 << 10
+			tr.ForceAttemptHTTP2 = true
+		})
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			return
+		}
+		c, _ := httpx.New(httpx.WithBaseURL(srv.URL), opt)
+		resp, err := c.Get(context.Background(), "/")
+		if err != nil {
+			fmt.Printf("  ✗ %v\n", err)
+			return
+		}
+		fmt.Printf("  ✓ DisableCompression=%-5v Accept-Encoding=%s\n", disable, resp.String())
+	}
+
+	// A wrapped transport hides its *http.Transport; configure it before wrapping.
+	wrapped := newPoolMetrics(nil).Instrument(&http.Transport{})
+	_, err := withTransportConfig(wrapped, func(tr *http.Transport) { tr.DisableCompression = true })
+	fmt.Printf("  ✓ wrapped transport rejected: %v\n", err)
+}
+
+// ---
+
+// errNotHTTPTransport is returned by withTransportConfig for a RoundTripper
+// it cannot configure.
+var errNotHTTPTransport = errors.New("transport config: transport is not an *http.Transport")
+
+// withTransportConfig returns an option that installs a copy of rt with fn
+// applied, for settings WithConnectionPool doesn't expose. A nil rt starts
+// from http.DefaultTransport. Apply it after any option that sets the
+// transport, since the last WithTransport wins.
+func withTransportConfig(rt http.RoundTripper, fn func(*http.Transport)) (httpx.Option, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	tr, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("%w: got %T", errNotHTTPTransport, rt)
+	}
+	tr = tr.Clone()
+	fn(tr)
+	return httpx.WithTransport(tr), nil
+}
+
+// preconnect dials each base URL's host through tr's dialer in the
+// background and hands those connections to tr's first requests, so they
+// skip the TCP handshake. The returned function waits for the dials to
+// finish. Warm connections are used once; if the server has closed one in
+// the meantime, that request fails like any stale pooled connection would.
+func preconnect(tr *http.Transport, baseURLs ...string) (wait func()) {
+	dial := tr.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	p := &preconnectDialer{dial: dial, warm: map[string][]net.Conn{}}
+	tr.DialContext = p.DialContext
+
+	var wg sync.WaitGroup
+	for _, raw := range baseURLs {
+		addr, err := dialAddr(raw)
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := dial(context.Background(), "tcp", addr)
+			if err != nil {
+				return // the first request will dial normally
+			}
+			p.mu.Lock()
+			p.warm[addr] = append(p.warm[addr], conn)
+			p.mu.Unlock()
+		}()
+	}
+	return wg.Wait
+}
+
+// preconnectDialer serves pre-dialed connections before dialing new ones.
+type preconnectDialer struct {
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	mu   sync.Mutex
+	warm map[string][]net.Conn // by host:port
+}
+
+func (p *preconnectDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	p.mu.Lock()
+	if conns := p.warm[addr]; len(conns) > 0 {
+		conn := conns[0]
+		p.warm[addr] = conns[1:]
+		p.mu.Unlock()
+		return conn, nil
+	}
+	p.mu.Unlock()
+	return p.dial(ctx, network, addr)
+}
+
+// dialAddr turns a base URL into host:port, filling in the scheme's port.
+func dialAddr(raw string) (string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	port := "80"
+	if u.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(u.Hostname(), port), nil
+}
+
+// connPoolStats is a snapshot of one host's connections. Idle is Total
+// minus Active: connections the transport holds for reuse.
+type connPoolStats struct {
+	Active int
+	Idle   int
+	Total  int
+}
+
+// poolMetrics tracks connections per host:port for an instrumented
+// http.Transport: Total from dials and closes, Active from the time a
+// request gets a connection until its response body is closed. Feed
+// Stats, or the onChange hook, into gauges.
+type poolMetrics struct {
+	onChange func(host string, stats connPoolStats)
+
+	mu    sync.Mutex
+	hosts map[string]*connPoolStats
+}
+
+func newPoolMetrics(onChange func(host string, stats connPoolStats)) *poolMetrics {
+	return &poolMetrics{onChange: onChange, hosts: map[string]*connPoolStats{}}
+}
+
+// Stats returns a copy of the current per-host counts.
+func (m *poolMetrics) Stats() map[string]connPoolStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]connPoolStats, len(m.hosts))
+	for host, s := range m.hosts {
+		out[host] = *s
+	}
+	return out
+}
+
+func (m *poolMetrics) update(host string, active, total int) {
+	m.mu.Lock()
+	s := m.hosts[host]
+	if s == nil {
+		s = &connPoolStats{}
+		m.hosts[host] = s
+	}
+	s.Active += active
+	s.Total += total
+	s.Idle = max(s.Total-s.Active, 0)
+	snapshot := *s
+	m.mu.Unlock()
+
+	if m.onChange != nil {
+		m.onChange(host, snapshot)
+	}
+}
+
+// Instrument wraps tr's dialer to count connections and returns a
+// RoundTripper over tr that counts connections in use.
+func (m *poolMetrics) Instrument(tr *http.Transport) http.RoundTripper {
+	dial := tr.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	tr.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		m.update(addr, 0, 1)
+		return &trackedConn{Conn: conn, onClose: func() { m.update(addr, 0, -1) }}, nil
+	}
+
+	return httpx.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		host, err := dialAddr(req.URL.String())
+		if err != nil {
+			return tr.RoundTrip(req)
+		}
+		var gotConn atomic.Bool
+		trace := &httptrace.ClientTrace{GotConn: func(httptrace.GotConnInfo) {
+			gotConn.Store(true)
+			m.update(host, 1, 0)
+		}}
+		resp, err := tr.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+		if err != nil {
+			if gotConn.Load() {
+				m.update(host, -1, 0)
+			}
+			return nil, err
+		}
+		resp.Body = &trackedBody{ReadCloser: resp.Body, onClose: func() { m.update(host, -1, 0) }}
+		return resp, nil
+	})
+}
+
+// trackedConn runs onClose once when the connection is closed.
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	onClose func()
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(c.onClose)
+	return c.Conn.Close()
+}
+
+// trackedBody runs onClose once when the response body is closed.
+type trackedBody struct {
+	io.ReadCloser
+	once    sync.Once
+	onClose func()
+}
+
+func (b *trackedBody) Close() error {
+	b.once.Do(b.onClose)
+	return b.ReadCloser.Close()
+}
@@ -16,6 +16,9 @@
 //	go run main.go tracing
 //	go run main.go singleflight
 //	go run main.go mock
+//	go run main.go transport
+//	go run main.go logging
+//	go run main.go errors
 package main
 
 import (
@@ -27,12 +30,15 @@ import (
 	"github.com/n0l3r/httpx-example/examples/basic"
 	"github.com/n0l3r/httpx-example/examples/cache"
 	cb "github.com/n0l3r/httpx-example/examples/circuit_breaker"
+	errorsdemo "github.com/n0l3r/httpx-example/examples/errors"
+	"github.com/n0l3r/httpx-example/examples/logging"
 	"github.com/n0l3r/httpx-example/examples/middleware"
 	mockdemo "github.com/n0l3r/httpx-example/examples/mock_test"
 	rl "github.com/n0l3r/httpx-example/examples/rate_limiter"
 	"github.com/n0l3r/httpx-example/examples/retry"
 	"github.com/n0l3r/httpx-example/examples/singleflight"
 	"github.com/n0l3r/httpx-example/examples/tracing"
+	"github.com/n0l3r/httpx-example/examples/transport"
 )
 
 type demo struct {
@@ -51,6 +57,9 @@ var allDemos = []demo{
 	{"tracing", tracing.Run},
 	{"singleflight", singleflight.Run},
 	{"mock", mockdemo.Run},
+	{"transport", transport.Run},
+	{"logging", logging.Run},
+	{"errors", errorsdemo.Run},
 }
 
 func main() {